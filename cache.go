@@ -0,0 +1,172 @@
+package grider
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxBytes is the size limit of the cache created by NewCache.
+const DefaultCacheMaxBytes = 64 << 20
+
+// Cache holds grids rendered by Handler keyed by the request. Entries
+// expire after TTL and are removed by Invalidate by tags of the handler
+// stored them. Zero TTL means entries don't expire. The total size
+// of the rendered grids is limited, least recently used entries are
+// evicted to store new ones. It's safe for concurrent use.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	lru       *list.List // keys, most recently used first.
+	size      int
+	maxBytes  int
+	nextSweep time.Time
+}
+
+type cacheEntry struct {
+	r       *rendered
+	tags    []string
+	expires time.Time
+	elem    *list.Element
+}
+
+// NewCache returns the empty cache holding entries for ttl
+// and DefaultCacheMaxBytes of rendered grids.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]*cacheEntry),
+		lru:      list.New(),
+		maxBytes: DefaultCacheMaxBytes,
+	}
+}
+
+// SetMaxBytes sets the size limit of the rendered grids, zero means
+// no limit. Least recently used entries are evicted to fit the limit,
+// grids larger than the limit are not stored.
+func (c *Cache) SetMaxBytes(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes = n
+	c.evict(0)
+}
+
+// Size returns the size of the rendered grids in the cache.
+func (c *Cache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *Cache) get(key string) (*rendered, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.expired(e, c.now()) {
+		c.remove(key, e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e.elem)
+	return e.r, true
+}
+
+func (c *Cache) set(key string, r *rendered, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.entries[key]; ok {
+		c.remove(key, old)
+	}
+	if c.maxBytes > 0 && len(r.body) > c.maxBytes {
+		return
+	}
+
+	now := c.now()
+	e := cacheEntry{r: r, tags: tags}
+	if c.ttl > 0 {
+		e.expires = now.Add(c.ttl)
+
+		// expired entries are removed at most once per TTL.
+		if now.After(c.nextSweep) {
+			for k, e := range c.entries {
+				if c.expired(e, now) {
+					c.remove(k, e)
+				}
+			}
+			c.nextSweep = now.Add(c.ttl)
+		}
+	}
+
+	c.evict(len(r.body))
+	e.elem = c.lru.PushFront(key)
+	c.entries[key] = &e
+	c.size += len(r.body)
+}
+
+// evict removes least recently used entries until n bytes more fit
+// the limit.
+func (c *Cache) evict(n int) {
+	for c.maxBytes > 0 && c.size+n > c.maxBytes && c.lru.Len() > 0 {
+		key := c.lru.Back().Value.(string)
+		c.remove(key, c.entries[key])
+	}
+}
+
+func (c *Cache) remove(key string, e *cacheEntry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, key)
+	c.size -= len(e.r.body)
+}
+
+func (c *Cache) expired(e *cacheEntry, now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Invalidate removes entries having any of tags.
+func (c *Cache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, e := range c.entries {
+		for _, t := range e.tags {
+			if containsString(tags, t) {
+				c.remove(k, e)
+				break
+			}
+		}
+	}
+}
+
+// Purge removes all entries.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.entries = make(map[string]*cacheEntry)
+	c.lru.Init()
+	c.size = 0
+	c.mu.Unlock()
+}
+
+// Len returns the number of entries including expired ones not removed yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func containsString(a []string, s string) bool {
+	for i := range a {
+		if a[i] == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Command gridergen generates TypeScript definitions or JSON Schema
+// of the grid/page protocol served by package grider.
+//
+// Usage:
+//
+//	gridergen -format ts -o grider.d.ts
+//	gridergen -format schema -o grider.schema.json
+//
+// Applications having own widget types should build own copy of
+// the command registering the widgets with grider.RegisterWidget.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golangkit/grider"
+)
+
+func main() {
+	format := flag.String("format", "ts", "output format: ts or schema")
+	out := flag.String("o", "", "output file name, stdout if empty")
+	flag.Parse()
+
+	if err := run(*format, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "gridergen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(format, out string) error {
+	var gen func(io.Writer) error
+	switch format {
+	case "ts":
+		gen = grider.GenerateTypeScript
+	case "schema":
+		gen = grider.GenerateJSONSchema
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	if out == "" {
+		return gen(os.Stdout)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	if err := gen(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
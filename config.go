@@ -0,0 +1,168 @@
+package grider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// FormatFunc converts value v to string using layout from the struct
+// field tag attribute fmt. The layout can be empty.
+type FormatFunc func(v interface{}, layout string) string
+
+// Config holds settings used to build and render grids. Different
+// tenants served by one process can use own Config.
+//
+// A Config must not be modified after it's passed to New or NewContext.
+// Use Clone to derive a new one.
+type Config struct {
+	// LinkPrefix is added to the links what do not start with "http".
+	LinkPrefix string
+
+	// TagName is the struct field tag key holding column attributes.
+	TagName string
+
+	// DateLayouts maps layout names used by the tag attribute fmt
+	// (datehms, datehm, date) to time layouts.
+	DateLayouts map[string]string
+
+	// Locale is a BCP 47 language tag, as instance "ru" or "pl-PL".
+	Locale string
+
+	// Formatters maps the type name as returned by reflect.Type.String()
+	// to the function converting values of the type to string.
+	Formatters map[string]FormatFunc
+
+	// Debug turns on validation of grids and pages before JSON serialization.
+	Debug bool
+
+	// CursorSecret is the key signing pagination cursor tokens.
+	CursorSecret []byte
+
+	// Overrides holds column attributes replacing the struct field tags.
+	// The registry is shared by clones of the config.
+	Overrides *Overrides
+}
+
+// Clone returns a deep copy of the config.
+func (c *Config) Clone() *Config {
+	res := *c
+
+	res.DateLayouts = make(map[string]string, len(c.DateLayouts))
+	for k, v := range c.DateLayouts {
+		res.DateLayouts[k] = v
+	}
+
+	res.Formatters = make(map[string]FormatFunc, len(c.Formatters))
+	for k, v := range c.Formatters {
+		res.Formatters[k] = v
+	}
+	return &res
+}
+
+func (c *Config) tagName() string {
+	if c.TagName == "" {
+		return FieldTagLabel
+	}
+	return c.TagName
+}
+
+// defaultConfig holds *Config used if no other config is given.
+// The value stored is never modified, setters store a modified copy.
+var (
+	defaultConfig   atomic.Value
+	defaultConfigMu sync.Mutex
+)
+
+func init() {
+	// TagName is left empty, so FieldTagLabel changed by the application
+	// still applies.
+	defaultConfig.Store(&Config{
+		DateLayouts: map[string]string{
+			"datehms": "02.01.2006 15:04:05",
+			"datehm":  "02.01.2006 15:04",
+			"date":    "02.01.2006",
+		},
+		Formatters: map[string]FormatFunc{},
+	})
+}
+
+// DefaultConfig returns a copy of the config used by grids created
+// without WithConfig.
+func DefaultConfig() *Config {
+	return defaultConfig.Load().(*Config).Clone()
+}
+
+// SetDefaultConfig replaces the config used by grids created
+// without WithConfig.
+func SetDefaultConfig(c *Config) {
+	defaultConfigMu.Lock()
+	defaultConfig.Store(c.Clone())
+	defaultConfigMu.Unlock()
+}
+
+// updateDefaultConfig applies f to a copy of the default config and
+// stores the result as the default config.
+func updateDefaultConfig(f func(*Config)) {
+	defaultConfigMu.Lock()
+	c := defaultConfig.Load().(*Config).Clone()
+	f(c)
+	defaultConfig.Store(c)
+	defaultConfigMu.Unlock()
+}
+
+func loadDefaultConfig() *Config {
+	return defaultConfig.Load().(*Config)
+}
+
+type configKey struct{}
+
+// NewContext returns a copy of ctx holding the config c.
+func NewContext(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// FromContext returns the config stored in ctx by NewContext or
+// the default config.
+func FromContext(ctx context.Context) *Config {
+	if c, ok := ctx.Value(configKey{}).(*Config); ok && c != nil {
+		return c
+	}
+	return loadDefaultConfig()
+}
+
+// WithConfig sets config used by the grid.
+func WithConfig(c *Config) func(*Option) {
+	return func(s *Option) {
+		s.config = c
+	}
+}
+
+// WithContext sets config used by the grid from ctx.
+// See NewContext.
+func WithContext(ctx context.Context) func(*Option) {
+	return func(s *Option) {
+		s.config = FromContext(ctx)
+	}
+}
+
+// SetConfig sets config used by the page.
+func (p *Page) SetConfig(c *Config) {
+	p.cfg = c
+}
+
+// config returns the page's config or the default config.
+func (p *Page) config() *Config {
+	if p.cfg != nil {
+		return p.cfg
+	}
+	return loadDefaultConfig()
+}
+
+// config returns the grid's config or the default config.
+func (g *Grid) config() *Config {
+	if g.option.config != nil {
+		return g.option.config
+	}
+	return loadDefaultConfig()
+}
@@ -0,0 +1,352 @@
+package grider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor is returned if the cursor token is malformed
+	// or its signature doesn't match.
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+	// ErrCursorMismatch is returned if the cursor was created for
+	// another grid or another sort.
+	ErrCursorMismatch = errors.New("pagination cursor doesn't match the grid sort")
+)
+
+// PageInfo describes the page of the grid paginated by cursor.
+// Total is set if the number of rows is known.
+type PageInfo struct {
+	HasNext    bool   `json:"hasNext"`
+	HasPrev    bool   `json:"hasPrev"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// Cursor is the position in the rows sorted by Keys. Values are
+// the sort key values of the row the page starts after. Backward
+// cursor reads rows before the position.
+//
+// The last sort key is expected to be unique, as instance the row id,
+// and the sort key columns are expected to be not null.
+type Cursor struct {
+	Grid     string
+	Keys     []SortKey
+	Values   []interface{}
+	Backward bool
+}
+
+// cursorPayload is the signed content of the cursor token.
+type cursorPayload struct {
+	Grid     string            `json:"g,omitempty"`
+	Keys     []SortKey         `json:"k"`
+	Values   []json.RawMessage `json:"v"`
+	Times    []int             `json:"t,omitempty"` // positions of time.Time values
+	Backward bool              `json:"b,omitempty"`
+}
+
+// EncodeCursor returns the opaque URL safe token of the cursor signed
+// by HMAC-SHA256 with secret.
+func EncodeCursor(secret []byte, c Cursor) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("cursor secret is empty")
+	}
+
+	p := cursorPayload{Grid: c.Grid, Keys: c.Keys, Backward: c.Backward}
+	for i, v := range c.Values {
+		if _, ok := v.(time.Time); ok {
+			p.Times = append(p.Times, i)
+		}
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		p.Values = append(p.Values, buf)
+	}
+
+	buf, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(buf) + "." + enc.EncodeToString(cursorMAC(secret, buf)), nil
+}
+
+// DecodeCursor verifies the signature of the token and returns
+// the cursor. Numbers are returned as json.Number.
+func DecodeCursor(secret []byte, token string) (Cursor, error) {
+	enc := base64.RawURLEncoding
+
+	i := strings.IndexByte(token, '.')
+	if i == -1 || len(secret) == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	buf, err := enc.DecodeString(token[:i])
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	mac, err := enc.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(mac, cursorMAC(secret, buf)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	c := Cursor{Grid: p.Grid, Keys: p.Keys, Backward: p.Backward, Values: make([]interface{}, len(p.Values))}
+	for i := range p.Values {
+		dec := json.NewDecoder(bytes.NewReader(p.Values[i]))
+		dec.UseNumber()
+		if err := dec.Decode(&c.Values[i]); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+
+	for _, i := range p.Times {
+		s, ok := c.Values[i].(string)
+		if !ok {
+			return Cursor{}, ErrInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+		c.Values[i] = t
+	}
+
+	if len(c.Keys) == 0 || len(c.Keys) != len(c.Values) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func cursorMAC(secret, buf []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// Check returns ErrCursorMismatch if the cursor wasn't created for
+// the grid and the sort keys.
+func (c *Cursor) Check(grid string, keys []SortKey) error {
+	if c.Grid != grid || len(c.Keys) != len(keys) {
+		return ErrCursorMismatch
+	}
+	for i := range keys {
+		if c.Keys[i] != keys[i] {
+			return ErrCursorMismatch
+		}
+	}
+	return nil
+}
+
+// Placeholder is the style of SQL query parameters.
+type Placeholder int
+
+const (
+	PlaceholderQuestion Placeholder = iota // ?
+	PlaceholderDollar                      // $1, $2, ...
+)
+
+// Where returns the keyset condition selecting rows after the cursor
+// position (before it for backward cursor) and the query arguments.
+// exprs maps the sort key columns to SQL expressions. The condition
+// is built in the expanded form usable by any database and with mixed
+// sort directions:
+//
+//	(a > $1) OR (a = $1 AND b < $2)
+//
+// argOffset is the number of the query arguments preceding the condition.
+// json.Number values are passed as int64 or float64, as drivers don't
+// accept them.
+func (c *Cursor) Where(exprs map[string]string, ph Placeholder, argOffset int) (string, []interface{}, error) {
+
+	if len(c.Values) != len(c.Keys) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	cols := make([]string, len(c.Keys))
+	for i := range c.Keys {
+		e, ok := exprs[c.Keys[i].Column]
+		if !ok {
+			return "", nil, errors.New("no SQL expression for cursor column " + c.Keys[i].Column)
+		}
+		cols[i] = e
+	}
+
+	var err error
+	values := make([]interface{}, len(c.Values))
+	for i, v := range c.Values {
+		if values[i], err = sqlArg(v); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// numbered parameters are reused, "?" are repeated.
+	var args []interface{}
+	if ph == PlaceholderDollar {
+		args = append(args, values...)
+	}
+	param := func(i int) string {
+		if ph == PlaceholderDollar {
+			return "$" + strconv.Itoa(argOffset+i+1)
+		}
+		args = append(args, values[i])
+		return "?"
+	}
+
+	var sb strings.Builder
+	for i := range c.Keys {
+		if i > 0 {
+			sb.WriteString(" OR ")
+		}
+		sb.WriteByte('(')
+		for j := 0; j < i; j++ {
+			sb.WriteString(cols[j] + " = " + param(j) + " AND ")
+		}
+
+		op := " > "
+		if c.Keys[i].Desc != c.Backward {
+			op = " < "
+		}
+		sb.WriteString(cols[i] + op + param(i) + ")")
+	}
+	return sb.String(), args, nil
+}
+
+// sqlArg converts json.Number to int64 or float64.
+func sqlArg(v interface{}) (interface{}, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v, nil
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, errors.New("cursor value " + string(n) + " is not a number")
+	}
+	return f, nil
+}
+
+// OrderBy returns ORDER BY clause of the cursor query. The order is
+// reversed for backward cursor, SetCursorPage restores it.
+func (c *Cursor) OrderBy(exprs map[string]string) (string, error) {
+	return orderBy(c.Keys, c.Backward, exprs)
+}
+
+// OrderBy returns ORDER BY clause of the first page query. exprs maps
+// the sort key columns to SQL expressions.
+func OrderBy(keys []SortKey, exprs map[string]string) (string, error) {
+	return orderBy(keys, false, exprs)
+}
+
+func orderBy(keys []SortKey, backward bool, exprs map[string]string) (string, error) {
+	parts := make([]string, len(keys))
+	for i := range keys {
+		e, ok := exprs[keys[i].Column]
+		if !ok {
+			return "", errors.New("no SQL expression for sort column " + keys[i].Column)
+		}
+		if keys[i].Desc != backward {
+			e += " DESC"
+		}
+		parts[i] = e
+	}
+	return "ORDER BY " + strings.Join(parts, ", "), nil
+}
+
+// SetCursorPage sets PaginationCursor and PageInfo of the grid holding
+// rows read by the query built for the cursor cur (nil for the first
+// page) with the limit pageSize+1. The extra row, if read, is removed
+// and tells that more rows exist. Rows of the backward cursor are
+// reversed. Cursors are signed by Config.CursorSecret. Total is
+// the number of all rows or nil if unknown.
+//
+// Cursor values are taken from source values of the cells, so the grid
+// must be created WithSourceValues and filled by the grid builders.
+func (g *Grid) SetCursorPage(cur *Cursor, keys []SortKey, pageSize int, total *int) error {
+
+	secret := g.config().CursorSecret
+	if len(secret) == 0 {
+		return errors.New("cursor secret is empty")
+	}
+	if len(g.values) != len(g.Rows) {
+		return errors.New("cursor pagination requires source values, see WithSourceValues")
+	}
+
+	cols := make([]int, len(keys))
+	for i := range keys {
+		if cols[i] = g.ColumnIndex(keys[i].Column); cols[i] == -1 {
+			return errors.New("unknown sort column " + keys[i].Column)
+		}
+	}
+
+	backward := cur != nil && cur.Backward
+	more := len(g.Rows) > pageSize
+	if more {
+		idx := make([]int, pageSize)
+		for i := range idx {
+			idx[i] = i
+		}
+		g.selectRows(idx)
+	}
+
+	if backward {
+		idx := make([]int, len(g.Rows))
+		for i := range idx {
+			idx[i] = len(idx) - 1 - i
+		}
+		g.selectRows(idx)
+	}
+
+	pi := PageInfo{Total: total}
+	if backward {
+		pi.HasNext, pi.HasPrev = true, more
+	} else {
+		pi.HasNext, pi.HasPrev = more, cur != nil
+	}
+
+	if len(g.Rows) > 0 {
+		var err error
+		if pi.HasNext {
+			c := Cursor{Grid: g.option.name, Keys: keys, Values: g.keyValues(len(g.Rows)-1, cols)}
+			if pi.NextCursor, err = EncodeCursor(secret, c); err != nil {
+				return err
+			}
+		}
+		if pi.HasPrev {
+			c := Cursor{Grid: g.option.name, Keys: keys, Values: g.keyValues(0, cols), Backward: true}
+			if pi.PrevCursor, err = EncodeCursor(secret, c); err != nil {
+				return err
+			}
+		}
+	}
+
+	g.SetPaginationType(PaginationCursor)
+	g.PageInfo = &pi
+	return nil
+}
+
+// keyValues returns source values of the row in the columns cols.
+func (g *Grid) keyValues(row int, cols []int) []interface{} {
+	res := make([]interface{}, len(cols))
+	for i, col := range cols {
+		res[i] = g.sourceValue(row, col)
+	}
+	return res
+}
@@ -0,0 +1,320 @@
+package grider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Encoding is the wire format of the grid.
+//
+// EncodingJSON is the format of Grid.JSON: rows are arrays of cells.
+//
+// EncodingColumnar replaces "rows" by "data" holding arrays of
+// the column cells, one array per column in order of "columns":
+//
+//	{"columns": [...], ..., "encoding": "columnar", "data": [["1", "2"], ["New", "New"]]}
+//
+// EncodingDict is columnar, low cardinality columns hold distinct
+// values in "dict" and positions of the values in "index", other
+// columns hold cells in "values":
+//
+//	"data": [{"values": ["1", "2"]}, {"dict": ["New"], "index": [0, 0]}]
+//
+// Columnar encodings hold the number of rows in "rowCount", so rows
+// of the grid without columns are kept. "rowCount" is omitted if rows
+// are null.
+//
+// EncodingMsgPack is MessagePack (https://msgpack.org/) encoding of
+// the Grid.JSON document. Integers are encoded as integers, map keys
+// are sorted.
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingColumnar Encoding = "columnar"
+	EncodingDict     Encoding = "dict"
+	EncodingMsgPack  Encoding = "msgpack"
+)
+
+// ParseEncoding returns the encoding by name. The empty name means
+// EncodingJSON.
+func ParseEncoding(s string) (Encoding, error) {
+	switch e := Encoding(s); e {
+	case "":
+		return EncodingJSON, nil
+	case EncodingJSON, EncodingColumnar, EncodingDict, EncodingMsgPack:
+		return e, nil
+	}
+	return "", errors.New("unknown grid encoding " + s)
+}
+
+// ContentType returns MIME type of the encoding.
+func (e Encoding) ContentType() string {
+	if e == EncodingMsgPack {
+		return "application/x-msgpack"
+	}
+	return "application/json"
+}
+
+// DictColumn is the column of EncodingDict.
+type DictColumn struct {
+	Dict   []string `json:"dict,omitempty"`
+	Index  []int    `json:"index,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// encodedGrid is the grid with rows replaced by the encoded data.
+// Rows hides Grid.Rows.
+type encodedGrid struct {
+	*gridJSON
+	Rows     json.RawMessage `json:"rows,omitempty"`
+	Encoding Encoding        `json:"encoding,omitempty"`
+	RowCount *int            `json:"rowCount,omitempty"`
+	Data     interface{}     `json:"data,omitempty"`
+}
+
+// newEncodedGrid returns the grid g with rows replaced by data.
+func newEncodedGrid(g *Grid, e Encoding, data interface{}) encodedGrid {
+	eg := encodedGrid{gridJSON: g.jsonView(), Encoding: e, Data: data}
+	if g.Rows != nil {
+		n := len(g.Rows)
+		eg.RowCount = &n
+	}
+	return eg
+}
+
+// Encode writes the grid to w in the encoding e. The grid is validated
+// first if debug mode is on.
+func (g *Grid) Encode(w io.Writer, e Encoding) error {
+	if g.config().Debug {
+		if err := g.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var v interface{}
+	switch e {
+	case EncodingJSON, "":
+		v = g
+	case EncodingColumnar:
+		v = newEncodedGrid(g, e, g.columnCells())
+	case EncodingDict:
+		v = newEncodedGrid(g, e, g.dictColumns())
+	case EncodingMsgPack:
+		return g.encodeMsgPack(w)
+	default:
+		return errors.New("unknown grid encoding " + string(e))
+	}
+
+	// JSON encodings are written without trailing newline as by JSON.
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// columnCells returns cells of the rows by columns.
+func (g *Grid) columnCells() [][]string {
+	res := make([][]string, len(g.Columns))
+	for c := range res {
+		res[c] = make([]string, len(g.Rows))
+		for r := range g.Rows {
+			if c < len(g.Rows[r]) {
+				res[c][r] = g.Rows[r][c]
+			}
+		}
+	}
+	return res
+}
+
+// dictColumns returns cells by columns. Columns where the number of
+// distinct values is at most half of the rows are dictionary encoded.
+func (g *Grid) dictColumns() []DictColumn {
+	cols := g.columnCells()
+	res := make([]DictColumn, len(cols))
+	for c, cells := range cols {
+		pos := make(map[string]int)
+		var dict []string
+		for _, s := range cells {
+			if _, ok := pos[s]; !ok {
+				pos[s] = len(dict)
+				dict = append(dict, s)
+			}
+			if len(dict)*2 > len(cells) {
+				break
+			}
+		}
+
+		if len(cells) == 0 || len(dict)*2 > len(cells) {
+			res[c].Values = cells
+			continue
+		}
+
+		res[c].Dict = dict
+		res[c].Index = make([]int, len(cells))
+		for r, s := range cells {
+			res[c].Index[r] = pos[s]
+		}
+	}
+	return res
+}
+
+func (g *Grid) encodeMsgPack(w io.Writer) error {
+	buf, err := json.Marshal(encodedGrid{gridJSON: g.jsonView()})
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	// null rows are kept as null.
+	if g.Rows != nil {
+		rows := make([]interface{}, len(g.Rows))
+		for i := range g.Rows {
+			rows[i] = g.Rows[i]
+		}
+		doc["rows"] = rows
+	}
+
+	mw := newMsgpackWriter(w)
+	if err := mw.writeValue(doc); err != nil {
+		return err
+	}
+	return mw.flush()
+}
+
+// DecodeGrid reads the grid in the encoding e written by Grid.Encode.
+func DecodeGrid(r io.Reader, e Encoding) (*Grid, error) {
+	var g Grid
+
+	switch e {
+	case EncodingJSON, "":
+		err := json.NewDecoder(r).Decode(&g)
+		return &g, err
+	case EncodingColumnar, EncodingDict:
+		var data json.RawMessage
+		eg := encodedGrid{gridJSON: (*gridJSON)(&g), Data: &data}
+		if err := json.NewDecoder(r).Decode(&eg); err != nil {
+			return nil, err
+		}
+		if eg.Encoding != e {
+			return nil, errors.New("grid encoding " + string(eg.Encoding) + " expected to be " + string(e))
+		}
+		return &g, g.decodeColumns(e, data, eg.RowCount)
+	case EncodingMsgPack:
+		return decodeMsgPack(r)
+	}
+	return nil, errors.New("unknown grid encoding " + string(e))
+}
+
+// decodeColumns sets rows from the data of the columnar encodings.
+// count is the number of rows, nil if rows are null.
+func (g *Grid) decodeColumns(e Encoding, data json.RawMessage, count *int) error {
+
+	var cols [][]string
+	if e == EncodingColumnar {
+		if err := json.Unmarshal(data, &cols); err != nil {
+			return err
+		}
+	} else {
+		var dcs []DictColumn
+		if err := json.Unmarshal(data, &dcs); err != nil {
+			return err
+		}
+		cols = make([][]string, len(dcs))
+		for c := range dcs {
+			if dcs[c].Dict == nil {
+				cols[c] = dcs[c].Values
+				continue
+			}
+			cols[c] = make([]string, len(dcs[c].Index))
+			for r, i := range dcs[c].Index {
+				if i < 0 || i >= len(dcs[c].Dict) {
+					return errors.New("dictionary index out of range")
+				}
+				cols[c][r] = dcs[c].Dict[i]
+			}
+		}
+	}
+
+	if count == nil {
+		if len(cols) > 0 {
+			return errors.New("grid rows count is missing")
+		}
+		g.Rows = nil
+		return nil
+	}
+
+	n := *count
+	if n < 0 {
+		return errors.New("grid rows count is negative")
+	}
+	for c := range cols {
+		if len(cols[c]) != n {
+			return errors.New("columns have different number of cells")
+		}
+	}
+
+	g.Rows = make([][]string, n)
+	for r := range g.Rows {
+		g.Rows[r] = make([]string, len(cols))
+		for c := range cols {
+			g.Rows[r][c] = cols[c][r]
+		}
+	}
+	return nil
+}
+
+func decodeMsgPack(r io.Reader) (*Grid, error) {
+	v, err := newMsgpackReader(r).readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("msgpack: grid expected to be a map")
+	}
+
+	rows, ok := doc["rows"].([]interface{})
+	if !ok && doc["rows"] != nil {
+		return nil, errors.New("msgpack: rows expected to be an array")
+	}
+	delete(doc, "rows")
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var g Grid
+	if err := json.Unmarshal(buf, &g); err != nil {
+		return nil, err
+	}
+
+	if rows != nil {
+		g.Rows = make([][]string, len(rows))
+	}
+	for i := range rows {
+		cells, ok := rows[i].([]interface{})
+		if !ok {
+			return nil, errors.New("msgpack: row expected to be an array")
+		}
+		g.Rows[i] = make([]string, len(cells))
+		for j := range cells {
+			if g.Rows[i][j], ok = cells[j].(string); !ok {
+				return nil, errors.New("msgpack: cell expected to be a string")
+			}
+		}
+	}
+	return &g, nil
+}
@@ -3,141 +3,629 @@ package grider
 import (
 	"encoding/base64"
 	"errors"
-	"fmt"
-	"strings"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/xuri/excelize/v2"
 )
 
-// placeholder holds extracted placeholders from GridColumn.Href
-type placeholder struct {
-	text           string
-	locateToColumn int
+// SetLinkPrefix sets LinkPrefix of the default config.
+func SetLinkPrefix(s string) {
+	updateDefaultConfig(func(c *Config) { c.LinkPrefix = s })
 }
 
-var linkPrefix string
+// xlsxContentType is MIME type of Excel workbook.
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 
-func SetLinkPrefix(s string) {
-	linkPrefix = s
+// MetadataSheetName is the name of the sheet holding generation time
+// and applied filters. See WithMetadataSheet.
+const MetadataSheetName = "Info"
+
+// Column width limits in characters used by fitting column widths.
+const (
+	minColumnWidth = 8
+	maxColumnWidth = 60
+)
+
+// ExcelOption holds settings of Excel export.
+type ExcelOption struct {
+	title        string
+	subtitle     string
+	translate    func(string) string
+	noFreeze     bool
+	noAutoFilter bool
+	noAutoWidth  bool
+	metadata     bool
+	bom          bool
+	filters      []string
+	now          func() time.Time
+
+	// view state, see exportView.
+	rowIDs     []int
+	rowUIDs    []uuid.UUID
+	order      []string
+	visible    []string
+	sort       []SortKey
+	where      []Filter
+	withHidden bool
 }
 
-func getPlaceholders(gc []Column) map[int][]placeholder {
-	res := make(map[int][]placeholder)
+// WithSheetTitle adds title and subtitle rows above the header row.
+// Empty values are not written.
+func WithSheetTitle(title, subtitle string) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.title = title
+		s.subtitle = subtitle
+	}
+}
 
-	for i := range gc {
-		if gc[i].Type != "link" {
-			continue
-		}
+// WithHeaderTranslator sets function translating column titles
+// and sheet titles, as instance resource codes like "%OrderDate%".
+func WithHeaderTranslator(f func(string) string) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.translate = f
+	}
+}
 
-		ru := []rune(gc[i].Href)
-		start := -1
-		for j := range ru {
-			if ru[j] == '{' {
-				start = j
-				continue
-			}
-			if ru[j] == '}' {
-				if start == -1 {
-					continue
-				}
-				// placeholder text holds {id}
-				p := placeholder{text: string(ru[start : j+1]), locateToColumn: -1}
-				for k := range gc {
-					if gc[k].Name == p.text[1:len(p.text)-1] {
-						p.locateToColumn = k
-						break
-					}
-				}
-				res[i] = append(res[i], p)
-				start = -1
-			}
+// WithFrozenHeader sets whether the header row is frozen. Default true.
+func WithFrozenHeader(b bool) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.noFreeze = !b
+	}
+}
+
+// WithAutoFilter sets whether autofilter is applied to the data range. Default true.
+func WithAutoFilter(b bool) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.noAutoFilter = !b
+	}
+}
+
+// WithColumnAutoWidth sets whether column widths are fitted from content. Default true.
+func WithColumnAutoWidth(b bool) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.noAutoWidth = !b
+	}
+}
+
+// WithMetadataSheet adds the sheet "Info" listing generation time
+// and descriptions of the applied filters.
+func WithMetadataSheet(filters ...string) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.metadata = true
+		s.filters = append(s.filters, filters...)
+	}
+}
+
+// WithBOM sets whether WriteCSV writes UTF-8 byte order mark first,
+// so Excel opens non ASCII text correctly. Default false.
+func WithBOM(b bool) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.bom = b
+	}
+}
+
+// WithSelectedRows exports only rows having ID in ids or UID in uids.
+func WithSelectedRows(ids []int, uids []uuid.UUID) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.rowIDs = ids
+		s.rowUIDs = uids
+	}
+}
+
+// WithColumnOrder exports columns listed in names first, in the given order.
+func WithColumnOrder(names ...string) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.order = names
+	}
+}
+
+// WithVisibleColumns exports only columns listed in names.
+func WithVisibleColumns(names ...string) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.visible = names
+	}
+}
+
+// WithSortKeys exports rows sorted by the keys.
+func WithSortKeys(keys ...SortKey) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.sort = keys
+	}
+}
+
+// WithFilters exports only rows satisfying all filters. The filters
+// are listed in the metadata sheet.
+func WithFilters(filters ...Filter) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.where = filters
+		for i := range filters {
+			s.filters = append(s.filters, filters[i].String())
 		}
 	}
+}
 
-	return res
+// WithHiddenColumns exports also the columns marked as hidden.
+func WithHiddenColumns(b bool) func(*ExcelOption) {
+	return func(s *ExcelOption) {
+		s.withHidden = b
+	}
 }
 
-func (r *Grid) Excelize(fname string) (*DownloadResponse, error) {
+// columnTitle returns translated title of the column, the column name
+// if the title is empty.
+func (eo *ExcelOption) columnTitle(c Column) string {
+	if c.Title == "" {
+		return eo.translate(c.Name)
+	}
+	return eo.translate(c.Title)
+}
 
+func newExcelOption(opts []func(*ExcelOption)) *ExcelOption {
+	eo := ExcelOption{
+		translate: func(s string) string { return s },
+		now:       time.Now,
+	}
+	for _, f := range opts {
+		f(&eo)
+	}
+	return &eo
+}
+
+// Excelize exports the grid to Excel workbook having a single sheet.
+// The header row holds translated column titles. By default the header
+// is frozen, autofilter is applied and column widths are fitted.
+//
+// Options WithSelectedRows, WithColumnOrder, WithVisibleColumns,
+// WithSortKeys and WithFilters make the file match the user's view.
+func (r *Grid) Excelize(fname string, opts ...func(*ExcelOption)) (*DownloadResponse, error) {
+	f, err := r.excelFile(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newDownloadResponse(fname, f)
+}
+
+// WriteExcel writes the workbook built like Excelize does to w.
+func (r *Grid) WriteExcel(w io.Writer, opts ...func(*ExcelOption)) error {
+	f, err := r.excelFile(opts)
+	if err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+func (r *Grid) excelFile(opts []func(*ExcelOption)) (*excelize.File, error) {
+
+	eo := newExcelOption(opts)
 	f := excelize.NewFile()
-	// Create a new sheet.
-	sch := "Sheet1"
 
-	pos := make(map[string]int)
-	phs := getPlaceholders(r.Columns)
+	v, err := r.exportView(eo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.writeSheet(f, "Sheet1", eo); err != nil {
+		return nil, err
+	}
+
+	if err := eo.writeMetadata(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// exportView returns a copy of the grid having rows and columns
+// selected, ordered and filtered according to the view state in eo.
+// The grid itself is not modified.
+func (r *Grid) exportView(eo *ExcelOption) (*Grid, error) {
+	v := *r
+
+	if err := v.SelectRows(eo.rowIDs, eo.rowUIDs); err != nil {
+		return nil, err
+	}
+
+	if err := v.FilterRows(eo.where...); err != nil {
+		return nil, err
+	}
+
+	if err := v.SortRows(eo.sort...); err != nil {
+		return nil, err
+	}
+
+	// reordering allocates new Columns, so it's safe to change them.
+	v.ReorderColumns(eo.order...)
+
+	var visible map[string]bool
+	if len(eo.visible) > 0 {
+		visible = make(map[string]bool, len(eo.visible))
+		for _, name := range eo.visible {
+			visible[name] = true
+		}
+	}
 
-	k := 0
-	for i := range r.Columns {
-		pos[r.Columns[i].Name] = i
-		if r.Columns[i].Hidden {
+	for i := range v.Columns {
+		if eo.withHidden && v.Columns[i].Hidden {
+			// hidden columns are exported in addition to visible ones.
+			v.Columns[i].Hidden = false
 			continue
 		}
+		if visible != nil {
+			v.Columns[i].Hidden = !visible[v.Columns[i].Name]
+		}
+	}
+	return &v, nil
+}
+
+// sheetStyles holds style IDs used by writeSheet.
+type sheetStyles struct {
+	f        *excelize.File
+	title    int
+	subtitle int
+	header   map[string]int
+	cell     map[string]int
+	link     map[string]int
+}
+
+func newSheetStyles(f *excelize.File) (*sheetStyles, error) {
+	ss := sheetStyles{
+		f:      f,
+		header: make(map[string]int),
+		cell:   make(map[string]int),
+		link:   make(map[string]int),
+	}
+
+	var err error
+	ss.title, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return nil, err
+	}
+
+	ss.subtitle, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Italic: true}})
+	if err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
+
+// style returns style ID of the header, regular or link cell
+// having alignment align.
+func (ss *sheetStyles) style(kind string, align string) (int, error) {
+	m := ss.cell
+	s := excelize.Style{}
+
+	switch kind {
+	case "header":
+		m = ss.header
+		s.Font = &excelize.Font{Bold: true}
+		s.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E7E6E6"}}
+		s.Border = []excelize.Border{{Type: "bottom", Color: "#7F7F7F", Style: 1}}
+	case "link":
+		m = ss.link
+		s.Font = &excelize.Font{Color: "#1265BE", Underline: "single"}
+	}
+
+	if id, ok := m[align]; ok {
+		return id, nil
+	}
+
+	if align != "" {
+		s.Alignment = &excelize.Alignment{Horizontal: align}
+	}
+
+	id, err := ss.f.NewStyle(&s)
+	if err != nil {
+		return 0, err
+	}
+	m[align] = id
+	return id, nil
+}
+
+// writeSheet writes title, header and rows of non hidden columns
+// to the existing sheet sch. Columns are ordered by Pin as the grid
+// shows them, columns pinned to the left are frozen. Excel can't freeze
+// the right side, columns pinned to the right are written last.
+func (r *Grid) writeSheet(f *excelize.File, sch string, eo *ExcelOption) error {
+
+	lts, err := linkTemplates(r.Columns)
+	if err != nil {
+		return err
+	}
+
+	ss, err := newSheetStyles(f)
+	if err != nil {
+		return err
+	}
+
+	cols := r.pinnedColumns()
+
+	row := 1
+	if eo.title != "" {
+		if err := writeTitle(f, sch, row, len(cols), eo.translate(eo.title), ss.title); err != nil {
+			return err
+		}
+		row++
+	}
+
+	if eo.subtitle != "" {
+		if err := writeTitle(f, sch, row, len(cols), eo.translate(eo.subtitle), ss.subtitle); err != nil {
+			return err
+		}
+		row++
+	}
+
+	if row > 1 {
+		// empty row between titles and the header.
+		row++
+	}
+
+	visible := make([]Column, len(cols))
+	for k, i := range cols {
+		visible[k] = r.Columns[i]
+	}
+
+	groups := headerGroups(visible)
+	for _, level := range groups {
+		if err := writeHeaderGroups(f, sch, row, level, eo, ss); err != nil {
+			return err
+		}
+		row++
+	}
 
-		cell, err := excelize.CoordinatesToCellName(k+1, 2)
+	headerRow := row
+	widths := make([]int, len(cols))
+
+	for k, i := range cols {
+		title := eo.columnTitle(r.Columns[i])
+
+		cell, err := excelize.CoordinatesToCellName(k+1, headerRow)
 		if err != nil {
-			return nil, errors.New("excel coordinates to cell failed (columns)")
+			return errors.New("excel coordinates to cell failed (columns)")
 		}
-		if err := f.SetCellStr(sch, cell, r.Columns[i].Name); err != nil {
-			return nil, err
+		if err := f.SetCellStr(sch, cell, title); err != nil {
+			return err
 		}
-		k++
+
+		style, err := ss.style("header", r.Columns[i].Align)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sch, cell, cell, style); err != nil {
+			return err
+		}
+		widths[k] = utf8.RuneCountInString(title)
 	}
 
-	for row := range r.Rows {
-		k := 0
-		for col := range r.Rows[row] {
-			if r.Columns[col].Hidden {
+	prefix := r.config().LinkPrefix
+	for ri := range r.Rows {
+		row++
+		for k, col := range cols {
+			if col >= len(r.Rows[ri]) {
 				continue
 			}
-			cell, err := excelize.CoordinatesToCellName(k+1, (row)+3)
+
+			cell, err := excelize.CoordinatesToCellName(k+1, row)
 			if err != nil {
-				return nil, errors.New("excel coordinates to cell failed (rows)")
+				return errors.New("excel coordinates to cell failed (rows)")
+			}
+
+			v := r.Rows[ri][col]
+			if r.Columns[col].Type == "chips" {
+				v = chipsText(v)
+			}
+			if err := f.SetCellStr(sch, cell, v); err != nil {
+				return err
 			}
 
-			if err := f.SetCellStr(sch, cell, r.Rows[row][col]); err != nil {
-				return nil, err
+			if n := utf8.RuneCountInString(v); n > widths[k] {
+				widths[k] = n
 			}
 
-			if r.Columns[col].Type == "link" {
-				// 1. get href from columns
-				// 2. find to what column it has reference ({xxx})
-				// 3. get value from the row named with xxx.
-				// 4. replace value
-				// 5. if url does not start with http, add domain names from config_params.
-				ph, ok := phs[col]
-				if ok {
-
-					href := ""
-					if !strings.HasPrefix(r.Columns[col].Href, "http") {
-						href = linkPrefix + r.Columns[col].Href
-					} else {
-						href = r.Columns[col].Href
-					}
-					for j := range ph {
-						if ph[j].locateToColumn == -1 {
-							fmt.Printf("not found %#v\n", ph[j])
-							continue
-						}
-						href = strings.Replace(href, ph[j].text, r.Rows[row][ph[j].locateToColumn], -1)
-					}
-
-					if err := f.SetCellHyperLink(sch, cell, href, "External"); err != nil {
-						return nil, err
-					}
-					// Set underline and font color style for the cell.
-					style, err := f.NewStyle(`{"font":{"color":"#1265BE","underline":"single"}}`)
-					if err == nil {
-						err = f.SetCellStyle(sch, cell, cell, style)
-					}
-					if err != nil {
-						return nil, err
-					}
+			kind := "cell"
+			if lt, ok := lts[col]; ok {
+				kind = "link"
+				href := lt.Expand(prefix, r.Rows[ri])
+				if err := f.SetCellHyperLink(sch, cell, href, "External"); err != nil {
+					return err
 				}
 			}
-			k++
+
+			if kind == "cell" && r.Columns[col].Align == "" {
+				continue
+			}
+
+			style, err := ss.style(kind, r.Columns[col].Align)
+			if err != nil {
+				return err
+			}
+			if err := f.SetCellStyle(sch, cell, cell, style); err != nil {
+				return err
+			}
 		}
 	}
 
+	if len(cols) == 0 {
+		return nil
+	}
+
+	for k, w := range widths {
+		c := &visible[k]
+		if c.Width == 0 && eo.noAutoWidth {
+			continue
+		}
+
+		width := fitWidth(w)
+		if c.Width > 0 {
+			width = pixelsToWidth(c.Width)
+		} else if m := pixelsToWidth(c.MinWidth); m > width {
+			width = m
+		}
+
+		name, err := excelize.ColumnNumberToName(k + 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetColWidth(sch, name, name, float64(width)); err != nil {
+			return err
+		}
+	}
+
+	if !eo.noFreeze {
+		// columns pinned to the left stay visible on horizontal scroll.
+		pinned := 0
+		for pinned < len(visible) && visible[pinned].Pin == "left" {
+			pinned++
+		}
+
+		pane := "bottomLeft"
+		if pinned > 0 {
+			pane = "bottomRight"
+		}
+
+		topLeft, _ := excelize.CoordinatesToCellName(pinned+1, headerRow+1)
+		panes := `{"freeze":true,"split":false,"x_split":` + strconv.Itoa(pinned) +
+			`,"y_split":` + strconv.Itoa(headerRow) +
+			`,"top_left_cell":"` + topLeft + `","active_pane":"` + pane + `"}`
+		if err := f.SetPanes(sch, panes); err != nil {
+			return err
+		}
+	}
+
+	if !eo.noAutoFilter {
+		hcell, _ := excelize.CoordinatesToCellName(1, headerRow)
+		vcell, _ := excelize.CoordinatesToCellName(len(cols), row)
+		if err := f.AutoFilter(sch, hcell, vcell, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pinnedColumns returns positions of non hidden columns pinned
+// to the left, not pinned and pinned to the right.
+func (r *Grid) pinnedColumns() []int {
+	cols := r.visibleColumns()
+	rank := func(i int) int {
+		switch r.Columns[i].Pin {
+		case "left":
+			return 0
+		case "right":
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(cols, func(a, b int) bool {
+		return rank(cols[a]) < rank(cols[b])
+	})
+	return cols
+}
+
+// writeTitle writes the title to the first cell of the row merged
+// across n columns.
+func writeTitle(f *excelize.File, sch string, row, n int, title string, style int) error {
+	hcell, _ := excelize.CoordinatesToCellName(1, row)
+	if err := f.SetCellStr(sch, hcell, title); err != nil {
+		return err
+	}
+
+	if err := f.SetCellStyle(sch, hcell, hcell, style); err != nil {
+		return err
+	}
+
+	if n > 1 {
+		vcell, _ := excelize.CoordinatesToCellName(n, row)
+		return f.MergeCell(sch, hcell, vcell)
+	}
+	return nil
+}
+
+// writeHeaderGroups writes the group header row. Cells of the group
+// are merged across its columns.
+func writeHeaderGroups(f *excelize.File, sch string, row int, level []HeaderCell, eo *ExcelOption, ss *sheetStyles) error {
+
+	style, err := ss.style("header", "center")
+	if err != nil {
+		return err
+	}
+
+	for _, hc := range level {
+		hcell, _ := excelize.CoordinatesToCellName(hc.Start+1, row)
+		vcell, _ := excelize.CoordinatesToCellName(hc.Start+hc.Span, row)
+
+		if hc.Title != "" {
+			if err := f.SetCellStr(sch, hcell, eo.translate(hc.Title)); err != nil {
+				return err
+			}
+		}
+		if err := f.SetCellStyle(sch, hcell, vcell, style); err != nil {
+			return err
+		}
+		if hc.Span > 1 {
+			if err := f.MergeCell(sch, hcell, vcell); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pixelsToWidth converts the column width in pixels to the Excel
+// column width in characters.
+func pixelsToWidth(px int) int {
+	if px <= 0 {
+		return 0
+	}
+	return (px + 6) / 7
+}
+
+// fitWidth converts the content length to the column width.
+func fitWidth(n int) int {
+	n += 2
+	if n < minColumnWidth {
+		return minColumnWidth
+	}
+	if n > maxColumnWidth {
+		return maxColumnWidth
+	}
+	return n
+}
+
+// writeMetadata adds the metadata sheet if it's requested. The sheet
+// name is expected to be free: Page.Excelize reserves it.
+func (eo *ExcelOption) writeMetadata(f *excelize.File) error {
+	if !eo.metadata {
+		return nil
+	}
+
+	sch := MetadataSheetName
+	if f.GetSheetIndex(sch) != -1 {
+		return errors.New("sheet " + sch + " already exists")
+	}
+	f.NewSheet(sch)
+
+	lines := [][2]string{{eo.translate("Generated"), eo.now().Format(time.RFC3339)}}
+	for i := range eo.filters {
+		lines = append(lines, [2]string{eo.translate("Filter"), eo.filters[i]})
+	}
+
+	for i := range lines {
+		if err := f.SetCellStr(sch, cellName(1, i+1), lines[i][0]); err != nil {
+			return err
+		}
+		if err := f.SetCellStr(sch, cellName(2, i+1), lines[i][1]); err != nil {
+			return err
+		}
+	}
+
+	return f.SetColWidth(sch, "A", "B", 24)
+}
+
+func newDownloadResponse(fname string, f *excelize.File) (*DownloadResponse, error) {
 	buf, err := f.WriteToBuffer()
 	if err != nil {
 		return nil, err
@@ -145,7 +633,7 @@ func (r *Grid) Excelize(fname string) (*DownloadResponse, error) {
 
 	resp := DownloadResponse{
 		FileName:    fname,
-		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		ContentType: xlsxContentType,
 		Content:     base64.StdEncoding.EncodeToString(buf.Bytes()),
 	}
 	buf.Reset()
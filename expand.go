@@ -0,0 +1,232 @@
+package grider
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rendering of slice, array and map attributes is controlled by the tag
+// attribute expand:
+//
+//	expand=columns  one column per element or key
+//	expand=join     one column, elements joined by sep (default ", ")
+//	expand=chips    one column of type "chips" holding JSON array of elements
+//
+// The separator holding commas or spaces is quoted: sep=', '.
+//
+// For expand=columns the attribute keys lists map keys or element labels
+// separated by "|", as instance keys=Jan|Feb|Mar. Without keys arrays are
+// expanded by their length, slices by the maximal length and maps by keys
+// of all rows. The attribute etitle is the column title template where
+// {key} is replaced by the key or label and {n} by the element number.
+//
+// Attributes without expand are rendered as a single formatted value.
+
+// isCollection returns true if values of the field are rendered by
+// the collection rules.
+func isCollection(tf reflect.StructField, tag string) bool {
+	if tf.Anonymous {
+		return false
+	}
+
+	switch tf.Type.Kind() {
+	case reflect.Slice:
+		if tf.Type.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+	case reflect.Array, reflect.Map:
+	default:
+		return false
+	}
+
+	// named types like uuid.UUID keep their own formatting.
+	return tf.Type.Name() == "" || extractTagAttr(tag, "expand") != ""
+}
+
+// collectionColumns returns columns of the slice, array or map
+// attribute v.
+func (g *Grid) collectionColumns(parentAttribute, attribute, tag string, v reflect.Value) []Column {
+
+	// expanded columns hold single elements.
+	var et reflect.Type
+	if extractTagAttr(tag, "expand") == "columns" {
+		et = v.Type().Elem()
+	}
+	base := g.convertTagToGridColumn(parentAttribute, attribute, tag, et)
+
+	switch extractTagAttr(tag, "expand") {
+	case "columns":
+	case "chips":
+		base.Type = "chips"
+		return []Column{base}
+	default:
+		return []Column{base}
+	}
+
+	keys := expandKeys(v, tag)
+	if g.expanded == nil {
+		g.expanded = make(map[string][]string)
+	}
+	if prev, ok := g.expanded[base.Name]; ok {
+		keys = unionKeys(prev, keys, v.Kind() == reflect.Map)
+	}
+	g.expanded[base.Name] = keys
+
+	tmpl := extractTagAttr(tag, "etitle")
+	res := make([]Column, len(keys))
+	for i, k := range keys {
+		c := base
+		c.Name = g.joinAttributeNames(base.Name, k)
+		if tmpl != "" {
+			c.Title = strings.NewReplacer("{key}", k, "{n}", strconv.Itoa(i+1)).Replace(tmpl)
+		} else {
+			c.Title = g.columnTitle(c.Name)
+		}
+		res[i] = c
+	}
+	return res
+}
+
+// expandKeys returns keys or element labels of the expanded attribute.
+func expandKeys(v reflect.Value, tag string) []string {
+	if s := extractTagAttr(tag, "keys"); s != "" {
+		return strings.Split(s, "|")
+	}
+
+	n := 0
+	switch v.Kind() {
+	case reflect.Array:
+		n = v.Len()
+	case reflect.Slice:
+		if !v.IsNil() {
+			n = v.Len()
+		}
+	case reflect.Map:
+		var keys []string
+		iter := v.MapRange()
+		for iter.Next() {
+			keys = append(keys, fmt.Sprint(iter.Key().Interface()))
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i + 1)
+	}
+	return keys
+}
+
+// unionKeys returns keys present in a or b. Map keys are sorted, element
+// labels of the longer slice are returned.
+func unionKeys(a, b []string, isMap bool) []string {
+	if !isMap {
+		if len(a) > len(b) {
+			return a
+		}
+		return b
+	}
+
+	res := append([]string{}, a...)
+	for _, k := range b {
+		if !containsString(a, k) {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// collectionValues returns cells of the slice, array or map attribute v.
+func (g *Grid) collectionValues(name, tag string, v reflect.Value) []string {
+
+	cfg := g.config()
+	layout := extractTagAttr(tag, "fmt")
+
+	switch extractTagAttr(tag, "expand") {
+	case "columns":
+		keys := g.expanded[name]
+		res := make([]string, len(keys))
+		if v.Kind() == reflect.Map {
+			byKey := make(map[string]reflect.Value, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				byKey[fmt.Sprint(iter.Key().Interface())] = iter.Value()
+			}
+			for i, k := range keys {
+				if e, ok := byKey[k]; ok {
+					res[i] = cfg.formatElem(e, layout)
+				}
+			}
+			return res
+		}
+
+		for i := range keys {
+			if i < v.Len() {
+				res[i] = cfg.formatElem(v.Index(i), layout)
+			}
+		}
+		return res
+	case "join":
+		sep := extractTagAttr(tag, "sep")
+		if sep == "" {
+			sep = ", "
+		}
+		return []string{strings.Join(cfg.formatElems(v, layout), sep)}
+	case "chips":
+		buf, err := json.Marshal(cfg.formatElems(v, layout))
+		if err != nil {
+			return []string{""}
+		}
+		return []string{string(buf)}
+	}
+
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil() {
+		return []string{""}
+	}
+	return []string{cfg.formatAttribute(v, layout)}
+}
+
+// formatElems formats all elements of the slice or array v. Elements
+// of the map are formatted as "key: value" ordered by key.
+func (c *Config) formatElems(v reflect.Value, layout string) []string {
+	res := []string{}
+	if v.Kind() == reflect.Map {
+		iter := v.MapRange()
+		for iter.Next() {
+			res = append(res, fmt.Sprint(iter.Key().Interface())+": "+c.formatElem(iter.Value(), layout))
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		res = append(res, c.formatElem(v.Index(i), layout))
+	}
+	return res
+}
+
+func (c *Config) formatElem(v reflect.Value, layout string) string {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return ""
+	}
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return c.formatAttribute(v, layout)
+}
+
+// chipsText converts the cell of the column with type "chips"
+// to the text joined by ", ".
+func chipsText(s string) string {
+	var a []string
+	if err := json.Unmarshal([]byte(s), &a); err != nil {
+		return s
+	}
+	return strings.Join(a, ", ")
+}
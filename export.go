@@ -0,0 +1,133 @@
+package grider
+
+import (
+	"bufio"
+	"encoding/csv"
+	"html"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes non hidden columns of the grid to w as CSV. The first
+// record holds column titles, it's preceded by the byte order mark
+// if WithBOM is set. Columns are ordered as by WriteExcel: pinned to
+// the left first, pinned to the right last. Options selecting the view and translating
+// titles are applied as by Excelize, sheet options are ignored.
+func (g *Grid) WriteCSV(w io.Writer, opts ...func(*ExcelOption)) error {
+
+	eo := newExcelOption(opts)
+	v, err := g.exportView(eo)
+	if err != nil {
+		return err
+	}
+
+	if eo.bom {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	cols := v.pinnedColumns()
+	cw := csv.NewWriter(w)
+
+	rec := make([]string, len(cols))
+	for k, i := range cols {
+		rec[k] = eo.columnTitle(v.Columns[i])
+	}
+	if err := cw.Write(rec); err != nil {
+		return err
+	}
+
+	for _, row := range v.Rows {
+		for k, i := range cols {
+			rec[k] = ""
+			if i < len(row) {
+				rec[k] = row[i]
+			}
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHTML writes non hidden columns of the grid to w as HTML table.
+// Group headers are written as header rows, cells of the link columns
+// are written as anchors. Text is escaped. Options and the column order
+// are the same as of WriteCSV.
+func (g *Grid) WriteHTML(w io.Writer, opts ...func(*ExcelOption)) error {
+
+	eo := newExcelOption(opts)
+	v, err := g.exportView(eo)
+	if err != nil {
+		return err
+	}
+
+	lts, err := linkTemplates(v.Columns)
+	if err != nil {
+		return err
+	}
+
+	cols := v.pinnedColumns()
+	visible := make([]Column, len(cols))
+	for k, i := range cols {
+		visible[k] = v.Columns[i]
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<table>\n<thead>\n")
+	for _, level := range headerGroups(visible) {
+		bw.WriteString("<tr>")
+		for _, hc := range level {
+			bw.WriteString("<th")
+			if hc.Span > 1 {
+				bw.WriteString(` colspan="` + strconv.Itoa(hc.Span) + `"`)
+			}
+			bw.WriteString(">" + html.EscapeString(eo.translate(hc.Title)) + "</th>")
+		}
+		bw.WriteString("</tr>\n")
+	}
+
+	bw.WriteString("<tr>")
+	for _, c := range visible {
+		bw.WriteString("<th>" + html.EscapeString(eo.columnTitle(c)) + "</th>")
+	}
+	bw.WriteString("</tr>\n</thead>\n<tbody>\n")
+
+	for _, row := range v.Rows {
+		links := v.links(lts, row)
+		bw.WriteString("<tr>")
+		for _, i := range cols {
+			bw.WriteString("<td")
+			if a := v.Columns[i].Align; a != "" {
+				bw.WriteString(` align="` + html.EscapeString(a) + `"`)
+			}
+			bw.WriteByte('>')
+			switch {
+			case i < len(links) && links[i] != nil:
+				bw.WriteString(links[i].HTML())
+			case i < len(row):
+				bw.WriteString(html.EscapeString(row[i]))
+			}
+			bw.WriteString("</td>")
+		}
+		bw.WriteString("</tr>\n")
+	}
+
+	bw.WriteString("</tbody>\n</table>\n")
+	return bw.Flush()
+}
+
+// visibleColumns returns positions of non hidden columns.
+func (g *Grid) visibleColumns() []int {
+	var res []int
+	for i := range g.Columns {
+		if !g.Columns[i].Hidden {
+			res = append(res, i)
+		}
+	}
+	return res
+}
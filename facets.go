@@ -0,0 +1,388 @@
+package grider
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FacetValue is the distinct value of the column and the number
+// of rows having it.
+type FacetValue struct {
+	Value string `json:"value"`
+	Title string `json:"title,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facet holds statistics of the column: distinct values with counts
+// for enum, bool and text filters, min and max for number and date
+// filters, and the number of empty values. Min and Max are formatted
+// as the cells of the column.
+type Facet struct {
+	Column string       `json:"column"`
+	Values []FacetValue `json:"values,omitempty"`
+	Min    string       `json:"min,omitempty"`
+	Max    string       `json:"max,omitempty"`
+	Nulls  int          `json:"nulls"`
+}
+
+// facetRange returns true if the column facet holds min and max
+// instead of distinct values.
+func facetRange(c *Column) bool {
+	ft := filterTypeOf(c)
+	if c.Filter != nil {
+		ft = c.Filter.Type
+	}
+	return ft == FilterNumber || ft == FilterDate
+}
+
+// ComputeFacets computes facets of the columns cols over the grid rows
+// satisfying filters. As usual for faceted search, filters on the column
+// itself are ignored for its facet, so all values of the column stay
+// visible. Without cols facets are computed for the columns having
+// Filter of type enum, bool, number or date.
+//
+// Values are compared using source values of the cells kept by the grid
+// created WithSourceValues. Otherwise cells are parsed as numbers and
+// dates, empty cells and "-" of null.Time are counted in Nulls.
+func (g *Grid) ComputeFacets(filters []Filter, cols ...string) ([]Facet, error) {
+
+	if len(cols) == 0 {
+		for i := range g.Columns {
+			if fs := g.Columns[i].Filter; fs != nil && fs.Type != FilterText {
+				cols = append(cols, g.Columns[i].Name)
+			}
+		}
+	}
+
+	fcols := make([]int, len(filters))
+	for i := range filters {
+		if fcols[i] = g.ColumnIndex(filters[i].Column); fcols[i] == -1 {
+			return nil, errors.New("unknown filter column " + filters[i].Column)
+		}
+	}
+
+	// matched[i][row] is the result of the filter i on the row.
+	cfg := g.config()
+	matched := make([][]bool, len(filters))
+	for i := range filters {
+		matched[i] = make([]bool, len(g.Rows))
+		for row := range g.Rows {
+			m, err := filters[i].match(g.cell(row, fcols[i]), cfg)
+			if err != nil {
+				return nil, err
+			}
+			matched[i][row] = m
+		}
+	}
+
+	res := make([]Facet, 0, len(cols))
+	for _, name := range cols {
+		ci := g.ColumnIndex(name)
+		if ci == -1 {
+			return nil, errors.New("unknown facet column " + name)
+		}
+
+		var rows []int
+		for row := range g.Rows {
+			ok := true
+			for i := range filters {
+				if fcols[i] != ci && !matched[i][row] {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				rows = append(rows, row)
+			}
+		}
+
+		res = append(res, g.facet(ci, rows))
+	}
+	return res, nil
+}
+
+// ApplyFacets computes facets by ComputeFacets and attaches them
+// to the grid JSON.
+func (g *Grid) ApplyFacets(filters []Filter, cols ...string) error {
+	f, err := g.ComputeFacets(filters, cols...)
+	if err != nil {
+		return err
+	}
+	g.Facets = f
+	return nil
+}
+
+// facet computes the facet of the column ci over rows.
+func (g *Grid) facet(ci int, rows []int) Facet {
+	c := &g.Columns[ci]
+	res := Facet{Column: c.Name}
+	cfg := g.config()
+
+	if facetRange(c) {
+		min, max := -1, -1
+		for _, row := range rows {
+			v := g.sourceValue(row, ci)
+			if v == nil {
+				res.Nulls++
+				continue
+			}
+			if min == -1 || compareValues(cfg, v, g.sourceValue(min, ci)) < 0 {
+				min = row
+			}
+			if max == -1 || compareValues(cfg, v, g.sourceValue(max, ci)) > 0 {
+				max = row
+			}
+		}
+		if min != -1 {
+			res.Min, res.Max = g.cell(min, ci), g.cell(max, ci)
+		}
+		return res
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, row := range rows {
+		if g.sourceValue(row, ci) == nil {
+			res.Nulls++
+			continue
+		}
+		v := g.cell(row, ci)
+		if _, ok := counts[v]; !ok {
+			order = append(order, v)
+		}
+		counts[v]++
+	}
+
+	// static options are listed in the declared order even if
+	// no row has them.
+	if c.Filter != nil && len(c.Filter.Options) > 0 {
+		for _, o := range c.Filter.Options {
+			res.Values = append(res.Values, FacetValue{Value: o.Value, Title: o.Title, Count: counts[o.Value]})
+			delete(counts, o.Value)
+		}
+	}
+
+	start := len(res.Values)
+	for _, v := range order {
+		if n, ok := counts[v]; ok {
+			res.Values = append(res.Values, FacetValue{Value: v, Count: n})
+		}
+	}
+
+	rest := res.Values[start:]
+	sort.SliceStable(rest, func(i, j int) bool {
+		if rest[i].Count != rest[j].Count {
+			return rest[i].Count > rest[j].Count
+		}
+		return rest[i].Value < rest[j].Value
+	})
+	return res
+}
+
+// sourceValue returns the source value of the cell or the cell itself
+// if source values are unknown. Empty values are returned as nil,
+// see nullCell.
+func (g *Grid) sourceValue(row, col int) interface{} {
+	if len(g.values) != len(g.Rows) || col >= len(g.values[row]) {
+		if s := g.cell(row, col); !nullCell(s) {
+			return s
+		}
+		return nil
+	}
+	return nullValue(g.values[row][col])
+}
+
+// nullCell returns true if the cell holds the empty value: the empty
+// string or "-" written for null.Time.
+func nullCell(s string) bool {
+	return s == "" || s == "-"
+}
+
+// nullValue unwraps nullable and pointer values. It returns nil
+// for null and empty values.
+func nullValue(v interface{}) interface{} {
+	if dv, ok := v.(driver.Valuer); ok {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		var err error
+		if v, err = dv.Value(); err != nil {
+			return nil
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.String && rv.Len() == 0 {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// compareValues compares source values of the same column. Numbers
+// and times are compared by value, other values as strings.
+func compareValues(cfg *Config, a, b interface{}) int {
+	if r, ok := compareTyped(a, b); ok {
+		return r
+	}
+
+	sa, aok := a.(string)
+	sb, bok := b.(string)
+	if aok && bok {
+		return cfg.compareCells(sa, sb)
+	}
+	return strings.Compare(cfg.formatValue(a), cfg.formatValue(b))
+}
+
+// compareTyped compares a and b if both are numbers or both are times.
+func compareTyped(a, b interface{}) (int, bool) {
+	if x, ok := numberOf(a); ok {
+		if y, ok := numberOf(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// numberOf converts numeric values to float64.
+func numberOf(v interface{}) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// FacetQuery describes the SQL query computing the facet of the grid
+// column. Expr is the SQL expression of the column value, as instance
+// "o.state_id" or "date_trunc('day', o.created_at)". Expr isn't escaped
+// and must not hold user input.
+type FacetQuery struct {
+	Column string
+	Expr   string
+	Range  bool // min and max instead of distinct values
+}
+
+// SQL returns the query over from (a table name, joins or a subquery
+// in parentheses with alias) restricted by the optional where condition.
+//
+// Distinct values query returns rows (value, count), the null values
+// are counted in the row with NULL value. Range query returns
+// the single row (min, max, nulls).
+func (q FacetQuery) SQL(from, where string) string {
+	var sb strings.Builder
+
+	if q.Range {
+		sb.WriteString("SELECT MIN(" + q.Expr + "), MAX(" + q.Expr + "), COUNT(*) - COUNT(" + q.Expr + ") FROM " + from)
+	} else {
+		sb.WriteString("SELECT " + q.Expr + ", COUNT(*) FROM " + from)
+	}
+
+	if where != "" {
+		sb.WriteString(" WHERE " + where)
+	}
+
+	if !q.Range {
+		sb.WriteString(" GROUP BY " + q.Expr + " ORDER BY COUNT(*) DESC, " + q.Expr)
+	}
+	return sb.String()
+}
+
+// ApplySQLFacet reads the result of the query built by q.SQL and
+// adds the facet to the grid JSON. Values are formatted the same way
+// as cells read by ApplySQLRows. ApplySQLFacet does not close rows.
+func (g *Grid) ApplySQLFacet(q FacetQuery, rows *sql.Rows) error {
+
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	if len(cts) == 0 {
+		return errors.New("facet query returns no columns")
+	}
+
+	kind := sqlColumnKind(cts[0])
+	layout := ""
+	if kind == sqlDate {
+		layout = "date"
+	}
+
+	cfg := g.config()
+	format := func(dest interface{}) string {
+		return cfg.formatAttribute(reflect.ValueOf(kind.value(dest)), layout)
+	}
+
+	f := Facet{Column: q.Column}
+	for rows.Next() {
+		if q.Range {
+			min, max := kind.scanDest(), kind.scanDest()
+			if err := rows.Scan(min, max, &f.Nulls); err != nil {
+				return err
+			}
+			if nullValue(kind.value(min)) != nil {
+				f.Min, f.Max = format(min), format(max)
+			}
+			continue
+		}
+
+		v := kind.scanDest()
+		var n int
+		if err := rows.Scan(v, &n); err != nil {
+			return err
+		}
+		if nullValue(kind.value(v)) == nil {
+			f.Nulls += n
+			continue
+		}
+		f.Values = append(f.Values, FacetValue{Value: format(v), Count: n})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	g.Facets = append(g.Facets, f)
+	return nil
+}
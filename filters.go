@@ -0,0 +1,343 @@
+package grider
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterType is the data type of the column filter. It defines
+// the filter control shown by the frontend.
+type FilterType string
+
+const (
+	FilterText   FilterType = "text"
+	FilterNumber FilterType = "number"
+	FilterDate   FilterType = "date"
+	FilterEnum   FilterType = "enum"
+	FilterBool   FilterType = "bool"
+)
+
+// filterTypes lists all values of FilterType.
+var filterTypes = []FilterType{FilterText, FilterNumber, FilterDate, FilterEnum, FilterBool}
+
+// Date presets of the date filter.
+const (
+	PresetToday     = "today"
+	PresetYesterday = "yesterday"
+	PresetThisWeek  = "thisWeek"
+	PresetLastWeek  = "lastWeek"
+	PresetThisMonth = "thisMonth"
+	PresetLastMonth = "lastMonth"
+	PresetThisYear  = "thisYear"
+)
+
+// FilterOption is the value of the enum filter.
+type FilterOption struct {
+	Value string `json:"value"`
+	Title string `json:"title,omitempty"`
+}
+
+// FilterSpec describes the filter of the column. Options of the enum
+// filter are given by the static list Options or by the dictionary name
+// Dict like "RefBookType.Name" resolved by the frontend.
+//
+// The struct field tag attributes:
+//
+//	ftype=date                   filter type
+//	fops=eq|between|isnull       allowed operators
+//	foptions=new:New|paid:Paid   enum options, value or value:title
+//	fdict=RefBookType.Name       enum options dictionary
+//	fpresets=today|thisMonth     date presets
+//
+// Any of them makes the column filterable. Operators default to
+// the operators of the filter type.
+type FilterSpec struct {
+	Type    FilterType     `json:"type"`
+	Ops     []FilterOp     `json:"ops"`
+	Options []FilterOption `json:"options,omitempty"`
+	Dict    string         `json:"dict,omitempty"`
+	Presets []string       `json:"presets,omitempty"`
+}
+
+// defaultFilterOps returns operators allowed by default for
+// the filter type.
+func defaultFilterOps(ft FilterType) []FilterOp {
+	switch ft {
+	case FilterNumber, FilterDate:
+		return []FilterOp{FilterEq, FilterNe, FilterBetween, FilterIsNull}
+	case FilterEnum:
+		return []FilterOp{FilterEq, FilterNe, FilterIn, FilterIsNull}
+	case FilterBool:
+		return []FilterOp{FilterEq, FilterIsNull}
+	}
+	return []FilterOp{FilterEq, FilterNe, FilterContains, FilterIsNull}
+}
+
+// filterTypeOf returns the filter type matching the column type.
+func filterTypeOf(c *Column) FilterType {
+	switch c.Type {
+	case "number":
+		return FilterNumber
+	case "date":
+		return FilterDate
+	case "bool":
+		return FilterBool
+	}
+	return FilterText
+}
+
+// filterSpec returns the filter of the column creating it if needed.
+func (c *Column) filterSpec() *FilterSpec {
+	if c.Filter == nil {
+		c.Filter = &FilterSpec{}
+	}
+	return c.Filter
+}
+
+// setFilterTag sets the filter attribute of the struct field tag.
+// It returns false if the attribute isn't a filter attribute.
+func (c *Column) setFilterTag(k, v string) bool {
+	switch k {
+	case "ftype":
+		c.filterSpec().Type = FilterType(v)
+	case "fops":
+		fs := c.filterSpec()
+		for _, op := range strings.Split(v, "|") {
+			fs.Ops = append(fs.Ops, FilterOp(strings.TrimSpace(op)))
+		}
+	case "foptions":
+		fs := c.filterSpec()
+		for _, o := range strings.Split(v, "|") {
+			kv := strings.SplitN(o, ":", 2)
+			fo := FilterOption{Value: strings.TrimSpace(kv[0])}
+			if len(kv) == 2 {
+				fo.Title = strings.TrimSpace(kv[1])
+			}
+			fs.Options = append(fs.Options, fo)
+		}
+	case "fdict":
+		c.filterSpec().Dict = v
+	case "fpresets":
+		c.filterSpec().Presets = strings.Split(v, "|")
+	default:
+		return false
+	}
+	return true
+}
+
+// completeFilter fills the filter type and operators not given
+// by the tag.
+func (c *Column) completeFilter() {
+	fs := c.Filter
+	if fs == nil {
+		return
+	}
+
+	c.Filterable = true
+	if fs.Type == "" {
+		if len(fs.Options) > 0 || fs.Dict != "" {
+			fs.Type = FilterEnum
+		} else if len(fs.Presets) > 0 {
+			fs.Type = FilterDate
+		} else {
+			fs.Type = filterTypeOf(c)
+		}
+	}
+	if len(fs.Ops) == 0 {
+		fs.Ops = defaultFilterOps(fs.Type)
+	}
+}
+
+// allowedOps returns operators allowed for the column or nil if
+// the column is not filterable.
+func (c *Column) allowedOps() []FilterOp {
+	if c.Filter != nil {
+		return c.Filter.Ops
+	}
+	if c.Filterable {
+		return defaultFilterOps(filterTypeOf(c))
+	}
+	return nil
+}
+
+// ValidateFilters checks the filters received from the client against
+// filters declared by the columns. It returns ValidationErrors with
+// paths like "filters[0]" or nil.
+func (g *Grid) ValidateFilters(filters []Filter) error {
+	var ve ValidationErrors
+
+	for i, f := range filters {
+		path := index("filters", i)
+
+		ci := g.ColumnIndex(f.Column)
+		if ci == -1 {
+			ve.add(path, "unknown column %q", f.Column)
+			continue
+		}
+		c := &g.Columns[ci]
+
+		ops := c.allowedOps()
+		if ops == nil {
+			ve.add(path, "column %q is not filterable", f.Column)
+			continue
+		}
+		if !hasFilterOp(ops, f.Op) {
+			ve.add(path, "operator %q is not allowed for column %q", f.Op, f.Column)
+			continue
+		}
+
+		switch f.Op {
+		case FilterBetween:
+			if len(f.Values) != 2 {
+				ve.add(path, "operator between expects 2 values, got %d", len(f.Values))
+				continue
+			}
+		case FilterIn:
+			if len(f.Values) == 0 {
+				ve.add(path, "operator in expects values")
+				continue
+			}
+		}
+
+		if c.Filter == nil || len(c.Filter.Options) == 0 {
+			continue
+		}
+
+		vals := f.Values
+		if f.Op == FilterEq || f.Op == FilterNe {
+			vals = []string{f.Value}
+		}
+		for _, v := range vals {
+			if !c.Filter.hasOption(v) {
+				ve.add(path, "value %q is not an option of column %q", v, f.Column)
+			}
+		}
+	}
+
+	return ve.err()
+}
+
+func hasFilterOp(ops []FilterOp, op FilterOp) bool {
+	for i := range ops {
+		if ops[i] == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs *FilterSpec) hasOption(v string) bool {
+	for i := range fs.Options {
+		if fs.Options[i].Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFilterSpec checks the filter declaration of the column.
+func validateFilterSpec(path string, fs *FilterSpec, ve *ValidationErrors) {
+	if !containsString(enumStrings(filterTypes), string(fs.Type)) {
+		ve.add(path, "unknown filter type %q", fs.Type)
+	}
+
+	ops := enumStrings(filterOps)
+	for _, op := range fs.Ops {
+		if !containsString(ops, string(op)) {
+			ve.add(path, "unknown filter operator %q", op)
+		}
+	}
+
+	for _, p := range fs.Presets {
+		if _, _, ok := DatePresetRange(p, time.Time{}); !ok {
+			ve.add(path, "unknown date preset %q", p)
+		}
+	}
+}
+
+// DatePresetRange returns the first day of the preset period and
+// the first day after it relative to now.
+func DatePresetRange(preset string, now time.Time) (from, to time.Time, ok bool) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// weeks start on Monday.
+	week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch preset {
+	case PresetToday:
+		return day, day.AddDate(0, 0, 1), true
+	case PresetYesterday:
+		return day.AddDate(0, 0, -1), day, true
+	case PresetThisWeek:
+		return week, week.AddDate(0, 0, 7), true
+	case PresetLastWeek:
+		return week.AddDate(0, 0, -7), week, true
+	case PresetThisMonth:
+		return month, month.AddDate(0, 1, 0), true
+	case PresetLastMonth:
+		return month.AddDate(0, -1, 0), month, true
+	case PresetThisYear:
+		year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+		return year, year.AddDate(1, 0, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// compareCells compares cells a and b as numbers, as dates parsed
+// by the config date layouts or ISO 8601, or as strings.
+func (c *Config) compareCells(a, b string) int {
+	if r, ok := c.compareParsed(a, b); ok {
+		return r
+	}
+	return strings.Compare(a, b)
+}
+
+// compareParsed compares cells a and b if both are parsed as numbers
+// or as dates.
+func (c *Config) compareParsed(a, b string) (int, bool) {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if x, ok := c.parseDate(a); ok {
+		if y, ok := c.parseDate(b); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// parseDate parses s formatted by one of the config date layouts
+// or by ISO 8601.
+func (c *Config) parseDate(s string) (time.Time, bool) {
+	for _, l := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, true
+		}
+	}
+
+	for _, name := range []string{"datehms", "datehm", "date"} {
+		if l, ok := c.DateLayouts[name]; ok {
+			if t, err := time.Parse(l, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
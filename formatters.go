@@ -11,32 +11,31 @@ import (
 	"gopkg.in/guregu/null.v3"
 )
 
-var ffunc map[string]func(interface{}) string
-
-var formats = map[string]string{
-	"datehms": "02.01.2006\u200715:04:05",
-	"datehm":  "02.01.2006\u200715:04",
-	"date":    "02.01.2006",
-}
-
-func formatAttribute(src reflect.Value, layout string) string {
+// formatAttribute converts src to string. Layout is a name of
+// the date layout from c.DateLayouts or a fmt verb like "%.2f".
+func (c *Config) formatAttribute(src reflect.Value, layout string) string {
 
 	format := layout
 
 	if len(layout) > 0 && layout[0] != '%' {
-		format = formats[layout]
+		format = c.DateLayouts[layout]
 	}
 
 	t := src.Type()
+	tn := t.String()
+
+	if f, ok := c.Formatters[tn]; ok && src.CanInterface() {
+		return f(src.Interface(), layout)
+	}
+
 	v := src.Interface()
 
 	var res string
-	tn := t.String()
 	//fmt.Printf("formating=val %#v, layout=%s\n", layout)
 	switch tn {
 	case "time.Time":
 		if len(format) == 0 {
-			format = formats["datehm"]
+			format = c.DateLayouts["datehm"]
 		}
 		res = v.(time.Time).Format(format)
 	case "date.Date":
@@ -47,7 +46,7 @@ func formatAttribute(src reflect.Value, layout string) string {
 			return "-"
 		}
 		if len(format) == 0 {
-			format = formats["datehm"]
+			format = c.DateLayouts["datehm"]
 		}
 		res = t.Time.Format(format)
 	default:
@@ -6,5 +6,6 @@ require (
 	github.com/axkit/date v0.3.0
 	github.com/google/uuid v1.3.0
 	github.com/xuri/excelize/v2 v2.4.1
+	golang.org/x/text v0.3.8
 	gopkg.in/guregu/null.v3 v3.5.0
 )
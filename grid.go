@@ -2,13 +2,14 @@ package grider
 
 import (
 	"encoding/json"
-	"fmt"
-	"strings"
+	"errors"
 
 	"github.com/google/uuid"
 )
 
-// FieldTagLabel holds struct field tag key.
+// FieldTagLabel holds struct field tag key used if Config.TagName is empty.
+//
+// Deprecated: use Config.TagName.
 var FieldTagLabel = "grid"
 
 // Column describes grid column's properties.
@@ -27,6 +28,15 @@ type Column struct {
 	Icons      string `json:"icons,omitempty"`      // comma separated fa-* icon names
 	IconsAlign string `json:"ialign,omitempty"`     // default "" ("left") "right" - after text
 	Target     string `json:"target,omitempty"`     // default "" browser window target for opening link
+	Order      int    `json:"order,omitempty"`      // default 0 (struct field order)
+	Width      int    `json:"width,omitempty"`      // default 0 (auto) column width in pixels
+	MinWidth   int    `json:"minWidth,omitempty"`   // default 0 minimal column width in pixels
+	Pin        string `json:"pin,omitempty"`        // default "" (not pinned) "left" or "right"
+	Group      string `json:"group,omitempty"`      // default "" group header path like "Finance/Q1"
+	NoSearch   bool   `json:"noSearch,omitempty"`   // default false, true excludes the column from Search
+	Sort       string `json:"sort,omitempty"`       // default "" "natural" - numbers in text are compared by value
+
+	Filter *FilterSpec `json:"filter,omitempty"` // default nil, see FilterSpec
 }
 
 // Grid describes data and metadata for presenting grid.
@@ -37,13 +47,33 @@ type Grid struct {
 	RowIDs         []int          `json:"rowIds,omitempty"`
 	RowUIDs        []uuid.UUID    `json:"rowUids,omitempty"`
 	RowActions     [][]ActionCode `json:"rowActions,omitempty"`
+	RowLinks       [][]*Link      `json:"rowLinks,omitempty"`
+	RowHighlights  [][]Highlight  `json:"rowHighlights,omitempty"`
+	HeaderGroups   [][]HeaderCell `json:"headerGroups,omitempty"`
 	GridActions    []ActionCode   `json:"gridActions,omitempty"`
 	Action         ActionSet      `json:"action,omitempty"`
 	IsDownloadable bool           `json:"isDownloadable"`
 	IsFilterable   bool           `json:"isFilterable"`
-	NoPagination   bool           `json:"noPagination,omitempty"`
+	NoPagination   bool           `json:"noPagination,omitempty"` // written as PaginationType == PaginationWithout
 	PaginationType PaginationType `json:"paginationType"`
+	Paging         *Paging        `json:"paging,omitempty"`
+	PageInfo       *PageInfo      `json:"pageInfo,omitempty"`
+	Views          []ViewInfo     `json:"views,omitempty"`
+	ActiveView     string         `json:"activeView,omitempty"`
+	Facets         []Facet        `json:"facets,omitempty"`
 	option         Option
+
+	// expanded holds keys of the attributes expanded to columns.
+	expanded map[string][]string
+
+	// values holds source values of the cells before formatting.
+	// It's nil if the grid rows are set directly or the grid is
+	// created without WithSourceValues.
+	values [][]interface{}
+
+	// perm holds positions of the struct fields of the columns ordered
+	// by ApplySliceOfStruct. It's nil if columns are not reordered.
+	perm []int
 }
 
 type DownloadResponse struct {
@@ -56,6 +86,10 @@ type Option struct {
 	titlePrefix    string
 	isDownloadable bool
 	multiLang      bool
+	config         *Config
+	naming         NamingStrategy
+	name           string
+	sourceValues   bool
 }
 
 func WitTitlePrefix(prefix string) func(*Option) {
@@ -70,6 +104,17 @@ func WithDownloadOption(b bool) func(*Option) {
 	}
 }
 
+// WithSourceValues keeps source values of the cells converted by
+// ApplySliceOfStruct, ApplySliceOfMaps, ApplyJSONRecords and ApplySQLRows.
+// SortRows and ComputeFacets compare numbers and times by source values,
+// cursor pagination requires them. Without source values cells
+// of the columns with type "number" and "date" are parsed.
+func WithSourceValues() func(*Option) {
+	return func(s *Option) {
+		s.sourceValues = true
+	}
+}
+
 func WithI18n() func(*Option) {
 	return func(s *Option) {
 		s.multiLang = true
@@ -99,73 +144,50 @@ func (g *Grid) DeleteColumns(col []string) {
 
 	// delete elements from Columns and Rows.
 	k := 0
+	kept := make([]int, 0, len(g.Columns))
 	for i := range g.Columns {
 		if _, ok := idx[i]; ok {
 			continue
 		}
+		kept = append(kept, i)
 		g.Columns[k] = g.Columns[i]
 
 		for r := range g.Rows {
 			g.Rows[r][k] = g.Rows[r][i]
 		}
+		for r := range g.RowLinks {
+			g.RowLinks[r][k] = g.RowLinks[r][i]
+		}
+		for r := range g.values {
+			g.values[r][k] = g.values[r][i]
+		}
 		k++
 	}
 	g.Columns = g.Columns[:k]
 	for r := range g.Rows {
 		g.Rows[r] = g.Rows[r][:k]
 	}
+	for r := range g.RowLinks {
+		g.RowLinks[r] = g.RowLinks[r][:k]
+	}
+	for r := range g.values {
+		g.values[r] = g.values[r][:k]
+	}
+	g.remapHighlights(kept)
+	g.BuildHeaderGroups()
 
 	return
 }
 
+// JSON returns JSON representation of the grid. The grid is validated
+// first if debug mode is on.
 func (g *Grid) JSON() ([]byte, error) {
-	return json.Marshal(g)
-}
-
-func (g *Grid) ReplaceCellWithFullLinks() error {
-
-	pos := make(map[string]int)
-	phs := getPlaceholders(g.Columns)
-
-	for i := range g.Columns {
-		pos[g.Columns[i].Name] = i
-	}
-
-	for row := range g.Rows {
-		for col := range g.Rows[row] {
-			if g.Columns[col].Hidden {
-				continue
-			}
-
-			if g.Columns[col].Type != "link" {
-				continue
-			}
-			// 1. get href from columns
-			// 2. find to what column it has reference ({xxx})
-			// 3. get value from the row named with xxx.
-			// 4. replace value
-			// 5. if url does not start with http, add domain names from config_params.
-			ph, ok := phs[col]
-			if ok {
-				href := ""
-				if !strings.HasPrefix(g.Columns[col].Href, "http") {
-					href = "<a href=\"" + linkPrefix + g.Columns[col].Href + "\">" + g.Rows[row][col] + "</a>"
-				} else {
-					href = "<a href=\"" + g.Columns[col].Href + "\">" + g.Rows[row][col] + "</a>"
-				}
-				for j := range ph {
-					if ph[j].locateToColumn == -1 {
-						return fmt.Errorf("invalid placeholder %s", ph[j].text)
-					}
-
-					// replaces placeholder {col} with text
-					href = strings.Replace(href, ph[j].text, g.Rows[row][ph[j].locateToColumn], -1)
-					g.Rows[row][col] = href
-				}
-			}
+	if g.config().Debug {
+		if err := g.Validate(); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	return json.Marshal(g)
 }
 
 func (g *Grid) AssignActionSet(as ActionSet) error {
@@ -178,12 +200,34 @@ func (g *Grid) AssignActionSet(as ActionSet) error {
 	return g.Action.AssignActionValues(as)
 }
 
+// gridJSON is Grid without MarshalJSON.
+type gridJSON Grid
+
+// MarshalJSON writes the grid with NoPagination derived from
+// PaginationType. NoPagination set without PaginationType, as before
+// pagination types were added, writes PaginationWithout.
+func (g Grid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.jsonView())
+}
+
+// jsonView returns the copy of the grid having consistent pagination
+// attributes.
+func (g *Grid) jsonView() *gridJSON {
+	v := gridJSON(*g)
+	if v.NoPagination && v.PaginationType == PaginationServer {
+		v.PaginationType = PaginationWithout
+	}
+	v.NoPagination = v.PaginationType == PaginationWithout
+	return &v
+}
+
 type PaginationType int
 
 const (
 	PaginationServer  PaginationType = 0
 	PaginationClient  PaginationType = 1
 	PaginationWithout PaginationType = 2
+	PaginationCursor  PaginationType = 3
 )
 
 func (pt PaginationType) String() string {
@@ -194,6 +238,8 @@ func (pt PaginationType) String() string {
 		return "client"
 	case 2:
 		return "without"
+	case 3:
+		return "cursor"
 	}
 	return ""
 }
@@ -201,3 +247,18 @@ func (pt PaginationType) String() string {
 func (pt PaginationType) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + pt.String() + `"`), nil
 }
+
+func (pt *PaginationType) UnmarshalJSON(buf []byte) error {
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+
+	for t := PaginationServer; t <= PaginationCursor; t++ {
+		if t.String() == s {
+			*pt = t
+			return nil
+		}
+	}
+	return errors.New("unknown pagination type " + s)
+}
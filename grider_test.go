@@ -1,9 +1,35 @@
 package grider_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golangkit/grider"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"gopkg.in/guregu/null.v3"
 )
 
 func TestNewPage(t *testing.T) {
@@ -28,3 +54,2059 @@ func TestNewPage(t *testing.T) {
 
 	t.Logf("%#v", p)
 }
+
+func TestPageAssignActionSetPointerWidgets(t *testing.T) {
+
+	a := grider.ActionSet{
+		"Edit":   grider.Action{Code: "Edit"},
+		"Delete": grider.Action{Code: "Delete"},
+		"Call":   grider.Action{Code: "Call"},
+	}
+
+	g := grider.Grid{
+		Columns:    []grider.Column{{Name: "Name"}},
+		Rows:       [][]string{{"Robert"}},
+		RowActions: [][]grider.ActionCode{{"Edit"}},
+	}
+
+	gw := &grider.GridWidget{Grid: &g}
+	p := grider.Page{
+		Widgets: []grider.Widgeter{gw},
+		Tabs: []grider.Tab{{
+			Widgets: []grider.Widgeter{&grider.AttrValueWidget{
+				Widget: &grider.Widget{Actions: []grider.ActionCode{"Delete"}},
+				Lines:  []grider.Line{{Actions: []grider.ActionCode{"Call"}}},
+			}},
+		}},
+	}
+
+	if err := p.AssignActionSet(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Action) != 3 {
+		t.Errorf("expected 3 actions, got %d: %v", len(p.Action), p.Action)
+	}
+
+	if err := grider.AssignActionSet(gw, a); err != nil {
+		t.Fatal(err)
+	}
+
+	if gw.Widget == nil || gw.Action["Edit"].Code != "Edit" {
+		t.Errorf("expected action Edit assigned to the pointer widget, got %#v", gw.Widget)
+	}
+
+	if err := grider.AssignActionSet(grider.GridWidget{Grid: &g}, a); err == nil {
+		t.Error("expected error for value widget without *Widget")
+	}
+}
+
+func TestPageValidate(t *testing.T) {
+
+	g := grider.Grid{
+		Columns: []grider.Column{
+			{Name: "ID"},
+			{Name: "Name", Type: "link", Href: "/customers/{CustomerID}"},
+		},
+		Rows:       [][]string{{"1", "Robert"}, {"2"}},
+		RowIDs:     []int{1},
+		RowActions: [][]grider.ActionCode{{"Edit"}, {"Drop"}},
+	}
+
+	p := grider.Page{
+		Action: grider.ActionSet{"Edit": grider.Action{Code: "Edit"}},
+		Tabs: []grider.Tab{
+			{IsActive: true},
+			{IsActive: true, Widgets: []grider.Widgeter{
+				&grider.GridWidget{Widget: &grider.Widget{ID: 1}, Grid: &g},
+				grider.EmptyWidget{Widget: &grider.Widget{ID: 1}},
+			}},
+		},
+	}
+
+	err := p.Validate()
+	ve, ok := err.(grider.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	expected := map[string]bool{
+		"tabs[1]":                                  true,
+		"tabs[1].widgets[0].grid.columns[1]":       true,
+		"tabs[1].widgets[0].grid.rows[1]":          true,
+		"tabs[1].widgets[0].grid.rowIds":           true,
+		"tabs[1].widgets[0].grid.rowActions[1][0]": true,
+		"tabs[1].widgets[1]":                       true,
+	}
+
+	for i := range ve {
+		if !expected[ve[i].Path] {
+			t.Errorf("unexpected error: %s", ve[i].Error())
+		}
+		delete(expected, ve[i].Path)
+	}
+
+	for path := range expected {
+		t.Errorf("expected error at %s", path)
+	}
+}
+
+func TestValidateActions(t *testing.T) {
+
+	supported := grider.ActionSet{"Edit": grider.Action{Code: "Edit", Title: "Edit"}}
+
+	g := grider.Grid{
+		Columns:    []grider.Column{{Name: "ID"}},
+		Rows:       [][]string{{"1"}, {"2"}},
+		RowActions: [][]grider.ActionCode{{"Edit"}, {"Drop"}},
+	}
+
+	// the action set built from the rows is never assigned.
+	g.Action = grider.NewActionSet()
+	g.Action.Add(g.RowActions[0])
+	g.Action.Add(g.RowActions[1])
+
+	err := g.ValidateActions(supported)
+	ve, ok := err.(grider.ValidationErrors)
+	if !ok || len(ve) != 3 {
+		t.Fatalf("expected 3 errors, got %v", err)
+	}
+	if ve[0].Path != "action" || ve[1].Path != "action" || ve[2].Path != "rowActions[1][0]" {
+		t.Errorf("unexpected errors %v", ve)
+	}
+	if err := g.Validate(); err == nil {
+		t.Error("Validate expected to report not assigned actions")
+	}
+
+	g.RowActions[1] = nil
+	if err := g.AssignActionSet(supported); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ValidateActions(supported); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	p := grider.Page{
+		PageActions: []grider.ActionCode{"Print"},
+		Widgets:     []grider.Widgeter{grider.GridWidget{Grid: &g}},
+	}
+	if err := p.ValidateActions(supported); err == nil || err.Error() != `pageActions[0]: unsupported action code "Print"` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestReplaceCellWithFullLinks(t *testing.T) {
+
+	g := grider.Grid{
+		Columns: []grider.Column{
+			{Name: "ID", Hidden: true},
+			{Name: "Name", Type: "link", Href: "http://example.com/customers/{ID}?name={Name}", Target: "_blank"},
+		},
+		Rows: [][]string{{"1/2", "<script>&Co"}},
+	}
+
+	if err := g.ApplyLinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	l := g.RowLinks[0][1]
+	if l == nil || l.URL != "http://example.com/customers/1%2F2?name=%3Cscript%3E%26Co" || l.Text != "<script>&Co" {
+		t.Fatalf("unexpected link %#v", l)
+	}
+
+	if err := g.ReplaceCellWithFullLinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<a href="http://example.com/customers/1%2F2?name=%3Cscript%3E%26Co" target="_blank">&lt;script&gt;&amp;Co</a>`
+	if g.Rows[0][1] != expected {
+		t.Errorf("expected %s, got %s", expected, g.Rows[0][1])
+	}
+
+	g.Columns[1].Href = "/customers/{CustomerID}"
+	if err := g.ReplaceCellWithFullLinks(); err == nil {
+		t.Error("expected error for unknown placeholder")
+	}
+}
+
+func TestConfigPerGrid(t *testing.T) {
+
+	type customer struct {
+		ID   int
+		Name string `json:"name" grid:"type=link,href=/customers/{ID}"`
+	}
+
+	src := []customer{{ID: 7, Name: "Robert"}}
+
+	for _, prefix := range []string{"https://a.example.com", "https://b.example.com"} {
+		prefix := prefix
+		t.Run(prefix, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := grider.DefaultConfig()
+			cfg.LinkPrefix = prefix
+
+			g := grider.New(grider.WithConfig(cfg)).ApplySliceOfStruct(src)
+			if err := g.ApplyLinks(); err != nil {
+				t.Fatal(err)
+			}
+
+			if l := g.RowLinks[0][1]; l == nil || l.URL != prefix+"/customers/7" {
+				t.Errorf("unexpected link %#v", l)
+			}
+		})
+	}
+}
+
+func TestFieldTagLabel(t *testing.T) {
+
+	type customer struct {
+		ID   int    `ui:"title=Number"`
+		Name string `ui:"-"`
+	}
+
+	defer func(s string) { grider.FieldTagLabel = s }(grider.FieldTagLabel)
+	grider.FieldTagLabel = "ui"
+
+	g := grider.New().ApplySliceOfStruct([]customer{{ID: 1, Name: "Robert"}})
+	if len(g.Columns) != 1 || g.Columns[0].Title != "Number" {
+		t.Errorf("FieldTagLabel is ignored, columns %+v", g.Columns)
+	}
+}
+
+func TestPageConfig(t *testing.T) {
+
+	cfg := grider.DefaultConfig()
+	cfg.Debug = true
+
+	p := grider.Page{Tabs: []grider.Tab{{IsActive: true}, {IsActive: true}}}
+	if _, err := p.JSON(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	p.SetConfig(cfg)
+	if _, err := p.JSON(); err == nil {
+		t.Error("page expected to be validated in debug mode of its config")
+	}
+}
+
+func TestExcelizeView(t *testing.T) {
+
+	g := grider.Grid{
+		Columns:    []grider.Column{{Name: "ID"}, {Name: "Name"}, {Name: "Status"}},
+		Rows:       [][]string{{"1", "Robert", "New"}, {"2", "Anna", "Paid"}, {"3", "Boris", "New"}},
+		RowIDs:     []int{1, 2, 3},
+		RowActions: [][]grider.ActionCode{{"a1"}, {"a2"}, {"a3"}},
+	}
+
+	v := g
+	if err := v.SelectRows([]int{1, 3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SortRows(grider.SortKey{Column: "Name"}); err != nil {
+		t.Fatal(err)
+	}
+	v.ReorderColumns("Name")
+
+	if len(v.Rows) != 2 || v.Rows[0][0] != "Boris" || v.RowIDs[0] != 3 || v.RowActions[0][0] != "a3" {
+		t.Errorf("unexpected view: %v %v %v", v.Rows, v.RowIDs, v.RowActions)
+	}
+
+	if g.Rows[0][0] != "1" || len(g.Rows) != 3 || g.RowIDs[0] != 1 {
+		t.Errorf("source grid modified: %v %v", g.Rows, g.RowIDs)
+	}
+
+	g.Rows = append(g.Rows, []string{"4", "Alice", "New"}, []string{"5"})
+	g.RowIDs = append(g.RowIDs, 4, 5)
+	g.RowActions = append(g.RowActions, nil, nil)
+
+	resp, err := g.Excelize("orders.xlsx",
+		grider.WithSelectedRows([]int{1, 3, 4, 5}, nil),
+		grider.WithColumnOrder("Status", "Name"),
+		grider.WithVisibleColumns("Name", "Status"),
+		grider.WithSortKeys(grider.SortKey{Column: "Name"}),
+		grider.WithFilters(grider.Filter{Column: "Status", Op: grider.FilterEq, Value: "New"}),
+		grider.WithMetadataSheet(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := openWorkbook(t, resp)
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"Status", "Name"}, {"New", "Alice"}, {"New", "Boris"}, {"New", "Robert"}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected rows %q, got %q", expected, rows)
+	}
+
+	// the short row 5 has empty Status.
+	v = g
+	if err := v.FilterRows(grider.Filter{Column: "Status", Op: grider.FilterEq, Value: ""}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v.RowIDs, []int{5}) {
+		t.Errorf("unexpected filtered rows %v", v.RowIDs)
+	}
+}
+
+func TestApplyJSONRecords(t *testing.T) {
+
+	src := `[{"name": "Robert", "amount": 10.5, "tags": ["a"]}, {"id": 2, "name": null}]`
+
+	g := grider.New()
+	err := g.ApplyJSONRecords(strings.NewReader(src), grider.Column{Name: "id", Title: "ID", Hidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, c := range g.Columns {
+		names = append(names, c.Name)
+	}
+
+	if strings.Join(names, ",") != "id,name,amount,tags" {
+		t.Errorf("unexpected columns %v", names)
+	}
+
+	expected := [][]string{{"", "Robert", "10.5", `["a"]`}, {"2", "", "", ""}}
+	for i := range expected {
+		if strings.Join(g.Rows[i], "|") != strings.Join(expected[i], "|") {
+			t.Errorf("row %d: expected %q, got %q", i, expected[i], g.Rows[i])
+		}
+	}
+
+	m := grider.New().ApplySliceOfMaps([]map[string]interface{}{{"b": 1, "a": "x"}})
+	if m.Columns[0].Name != "a" || m.Columns[1].Align != "right" || m.Columns[1].Type != "number" {
+		t.Errorf("unexpected columns %v", m.Columns)
+	}
+
+	// numbers are sorted by value without source values.
+	g = grider.New()
+	if err := g.ApplyJSONRecords(strings.NewReader(`[{"n": 10.5}, {"n": 9}, {"n": 100}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SortRows(grider.SortKey{Column: "n"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(g.Rows); s != "[[9] [10.5] [100]]" {
+		t.Errorf("unexpected order %s", s)
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+
+	type customer struct {
+		Name    string
+		Address struct {
+			City     string
+			PostCode string `grid:"name=zip"`
+		}
+	}
+
+	cases := []struct {
+		ns       grider.NamingStrategy
+		expected string
+	}{
+		{grider.NamingGo, "Name,AddressCity,Addresszip"},
+		{grider.NamingSnake, "name,address_city,address_zip"},
+		{grider.NamingDotPath, "name,address.city,address.zip"},
+	}
+
+	for _, c := range cases {
+		g := grider.New(grider.WithNaming(c.ns)).ApplySliceOfStruct([]customer{{}})
+
+		names := []string{}
+		for _, col := range g.Columns {
+			names = append(names, col.Name)
+		}
+
+		if s := strings.Join(names, ","); s != c.expected {
+			t.Errorf("naming %d: expected %s, got %s", c.ns, c.expected, s)
+		}
+	}
+}
+
+func TestNamingDuplicates(t *testing.T) {
+
+	// NamingGo uses only the nearest parent: both cities are "AddressCity".
+	type customer struct {
+		Name    string
+		Address struct {
+			City string
+		}
+		Billing struct {
+			Address struct {
+				City string
+			}
+		}
+	}
+
+	g := grider.New().ApplySliceOfStruct([]customer{{Name: "Robert"}})
+	if len(g.Columns) != 3 || g.Columns[1].Name != g.Columns[2].Name {
+		t.Fatalf("unexpected columns %+v", g.Columns)
+	}
+	if ve, ok := g.Validate().(grider.ValidationErrors); !ok || len(ve) != 1 {
+		t.Errorf("expected duplicate column name error, got %v", g.Validate())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on duplicate snake case names")
+		}
+	}()
+
+	type pair struct {
+		AB string `grid:"name=a_b"`
+		A  struct {
+			B string
+		}
+	}
+	grider.New(grider.WithNaming(grider.NamingSnake)).ApplySliceOfStruct([]pair{{}})
+}
+
+func TestColumnLayoutTags(t *testing.T) {
+
+	type sales struct {
+		Region string `grid:"pin=left,width=120"`
+		Q1     int    `grid:"group=Finance/H1,order=2"`
+		Q2     int    `grid:"group=Finance/H1,order=3"`
+		Plan   int    `grid:"group=Finance,title=Planned,order=4"`
+		Note   string
+		ID     int `grid:"order=1,hidden=true"`
+	}
+
+	g := grider.New().ApplySliceOfStruct([]sales{{Region: "North", Q1: 1, Q2: 2, Plan: 3, Note: "n", ID: 7}})
+
+	names := []string{}
+	for _, c := range g.Columns {
+		names = append(names, c.Name)
+	}
+	if s := strings.Join(names, ","); s != "ID,Q1,Q2,Plan,Region,Note" {
+		t.Errorf("unexpected column order %s", s)
+	}
+	if s := strings.Join(g.Rows[0], ","); s != "7,1,2,3,North,n" {
+		t.Errorf("unexpected row %s", s)
+	}
+	if g.Columns[3].Title != "Planned" || g.Columns[4].Width != 120 || g.Columns[4].Pin != "left" {
+		t.Errorf("unexpected columns %+v", g.Columns)
+	}
+
+	// the hidden column ID isn't counted.
+	expected := [][]grider.HeaderCell{
+		{{Title: "Finance", Start: 0, Span: 3}, {Title: "", Start: 3, Span: 2}},
+		{{Title: "H1", Start: 0, Span: 2}, {Title: "", Start: 2, Span: 3}},
+	}
+	if !reflect.DeepEqual(g.HeaderGroups, expected) {
+		t.Errorf("unexpected header groups %+v", g.HeaderGroups)
+	}
+
+	if _, err := g.Excelize("sales.xlsx"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestColumnOrderSources(t *testing.T) {
+
+	defs := []grider.Column{{Name: "name", Order: 1}, {Name: "total", Pin: "right"}, {Name: "id", Pin: "left"}}
+	g := grider.New().ApplySliceOfMaps([]map[string]interface{}{{"id": 1, "name": "Robert", "total": 10, "city": "Riga"}}, defs...)
+
+	names := []string{}
+	for _, c := range g.Columns {
+		names = append(names, c.Name)
+	}
+	if s := strings.Join(names, ","); s != "name,total,id,city" {
+		t.Errorf("unexpected column order %s", s)
+	}
+
+	fakeResults["customers"] = fakeResult{
+		columns: []string{"id", "name"},
+		types:   []string{"INT8", "TEXT"},
+		scan:    []reflect.Type{reflect.TypeOf(int64(0)), reflect.TypeOf("")},
+		rows:    [][]driver.Value{{int64(7), "Anna"}},
+	}
+	db, err := sql.Open("grider-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("customers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var sg grider.Grid
+	if err := sg.ApplySQLRows(rows, map[string]grider.Column{"name": {Order: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if sg.Columns[0].Name != "name" || !reflect.DeepEqual(sg.Rows, [][]string{{"Anna", "7"}}) {
+		t.Errorf("unexpected sql grid %+v %q", sg.Columns, sg.Rows)
+	}
+
+	// pinned columns are exported left and right, the left ones are frozen.
+	resp, err := g.Excelize("customers.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := openWorkbook(t, resp)
+	header, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header[0], []string{"id", "name", "city", "total"}) {
+		t.Errorf("unexpected exported columns %q", header[0])
+	}
+	if xml := sheetXML(t, resp, "xl/worksheets/sheet1.xml"); !strings.Contains(xml, `xSplit="1"`) {
+		t.Errorf("expected frozen column id %s", xml)
+	}
+
+	type bad struct {
+		Name string `grid:"width=wide"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on wrong width")
+		}
+	}()
+	grider.New().ApplySliceOfStruct([]bad{{}})
+}
+
+func TestColumnOverrides(t *testing.T) {
+
+	dir := t.TempDir()
+	src := `{"Phone": {"hidden": true, "filterable": false}, "Name": {"title": "Customer", "href": "/customers/{Phone}"}}`
+	if err := os.WriteFile(filepath.Join(dir, "customers.json"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := grider.NewOverrides()
+	if err := o.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := grider.DefaultConfig()
+	c.Overrides = o
+
+	type customer struct {
+		Name  string
+		Phone string `grid:"ftype=text,fops=eq|contains"`
+	}
+
+	g := grider.New(grider.WithConfig(c), grider.WithName("customers")).ApplySliceOfStruct([]customer{{"Robert", "1"}})
+	if g.Columns[0].Title != "Customer" || g.Columns[0].Href != "/customers/{Phone}" || !g.Columns[1].Hidden {
+		t.Errorf("overrides not applied: %+v", g.Columns)
+	}
+	if c := g.Columns[1]; c.Filterable || c.Filter != nil {
+		t.Errorf("filter expected to be removed: %+v", c)
+	}
+	if err := g.ValidateFilters([]grider.Filter{{Column: "Phone", Op: grider.FilterEq, Value: "1"}}); err == nil {
+		t.Error("expected error for not filterable column")
+	}
+
+	g = grider.New(grider.WithConfig(c)).ApplySliceOfStruct([]customer{{"Robert", "1"}})
+	if g.Columns[0].Title != "Name" || g.Columns[1].Hidden {
+		t.Errorf("overrides applied to unnamed grid: %+v", g.Columns)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "orders.json"), []byte(`{"Sum": {"hiden": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.LoadDir(dir); err == nil {
+		t.Error("expected error for unknown attribute")
+	}
+	if _, ok := o.Lookup("customers", "Phone"); !ok {
+		t.Error("overrides lost after failed load")
+	}
+}
+
+func TestViews(t *testing.T) {
+
+	ctx := context.Background()
+	stores := map[string]grider.ViewStore{
+		"memory": grider.NewMemoryViewStore(),
+		"file":   grider.NewFileViewStore(t.TempDir()),
+	}
+
+	width, hidden := 80, true
+	for name, s := range stores {
+		v := grider.View{
+			Name:    "My overdue orders",
+			Grid:    "orders",
+			Owner:   "robert",
+			Columns: []grider.ViewColumn{{Name: "Sum", Width: &width}, {Name: "Customer"}, {Name: "ID", Hidden: &hidden}},
+			Sort:    []grider.SortKey{{Column: "Sum", Desc: true}},
+			Filters: []grider.Filter{{Column: "State", Op: grider.FilterEq, Value: "overdue"}},
+		}
+		if err := s.Save(ctx, &v); err != nil {
+			t.Fatal(name, err)
+		}
+
+		g := grider.New(grider.WithName("orders"))
+		g.Columns = []grider.Column{{Name: "ID"}, {Name: "Client", Width: 150}, {Name: "Sum"}, {Name: "State"}}
+		g.Rows = [][]string{{"1", "Robert", "10", "overdue"}, {"2", "Anna", "20", "paid"}, {"3", "Boris", "30", "overdue"}}
+		g.RowIDs = []int{1, 2, 3}
+
+		views, err := g.LoadViews(ctx, s, "robert", map[string]string{"Customer": "Client"})
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if len(g.Views) != 1 || g.Views[0].ID != v.ID {
+			t.Errorf("%s: unexpected views %v", name, g.Views)
+		}
+
+		saved, err := s.Get(ctx, "orders", "robert", v.ID)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if len(saved.Columns) != 3 || saved.Columns[1].Name != "Client" {
+			t.Errorf("%s: view not migrated %+v", name, saved.Columns)
+		}
+
+		// the wrong view doesn't change the grid.
+		bad := views[0]
+		bad.Sort = []grider.SortKey{{Column: "Phone"}}
+		if err := g.ApplyView(bad); err == nil || g.Columns[0].Name != "ID" || g.Columns[0].Hidden || len(g.Rows) != 3 {
+			t.Errorf("%s: wrong view applied: %v %v", name, err, g.Columns)
+		}
+
+		if err := g.ApplyView(views[0]); err != nil {
+			t.Fatal(name, err)
+		}
+		if g.Columns[0].Name != "Sum" || g.Columns[0].Width != 80 || g.Rows[0][0] != "30" || g.RowIDs[0] != 3 || len(g.Rows) != 2 {
+			t.Errorf("%s: unexpected grid %v %v %v", name, g.Columns, g.Rows, g.RowIDs)
+		}
+		if g.Columns[1].Name != "Client" || g.Columns[1].Width != 150 || !g.Columns[2].Hidden {
+			t.Errorf("%s: unexpected columns %+v", name, g.Columns)
+		}
+
+		if err := s.Delete(ctx, "orders", "robert", v.ID); err != nil {
+			t.Fatal(name, err)
+		}
+		if _, err := s.Get(ctx, "orders", "robert", v.ID); err != grider.ErrViewNotFound {
+			t.Errorf("%s: expected ErrViewNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestFileViewStoreNames(t *testing.T) {
+
+	ctx := context.Background()
+	s := grider.NewFileViewStore(t.TempDir())
+	for _, grid := range []string{"..", "../orders", `a\b`, ""} {
+		if err := s.Save(ctx, &grider.View{Grid: grid, Owner: "robert"}); err == nil {
+			t.Errorf("grid name %q accepted", grid)
+		}
+		if _, err := s.List(ctx, "orders", grid); err == nil {
+			t.Errorf("owner name %q accepted", grid)
+		}
+	}
+}
+
+func TestFilterSpec(t *testing.T) {
+
+	type order struct {
+		State string    `grid:"foptions=new:New|paid:Paid"`
+		Sum   float64   `grid:"type=number,fops=between|isnull"`
+		Date  time.Time `grid:"fpresets=today|thisMonth,fmt=date"`
+		Note  string
+	}
+
+	d := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	g := grider.New().ApplySliceOfStruct([]order{
+		{"new", 10, d, ""},
+		{"paid", 25, d.AddDate(0, 1, 0), ""},
+	})
+
+	st := g.Columns[0].Filter
+	if st == nil || st.Type != grider.FilterEnum || len(st.Options) != 2 || !g.Columns[0].Filterable {
+		t.Fatalf("unexpected filter %+v", st)
+	}
+	if ft := g.Columns[2].Filter; ft == nil || ft.Type != grider.FilterDate || len(ft.Ops) == 0 {
+		t.Fatalf("unexpected date filter %+v", ft)
+	}
+	if g.Columns[3].Filter != nil {
+		t.Errorf("unexpected filter of column Note")
+	}
+
+	err := g.ValidateFilters([]grider.Filter{
+		{Column: "State", Op: grider.FilterIn, Values: []string{"new", "lost"}},
+		{Column: "Sum", Op: grider.FilterEq, Value: "10"},
+		{Column: "Note", Op: grider.FilterEq},
+		{Column: "Sum", Op: grider.FilterBetween, Values: []string{"5", "20"}},
+	})
+	ve, ok := err.(grider.ValidationErrors)
+	if !ok || len(ve) != 3 {
+		t.Fatalf("expected 3 validation errors, got %v", err)
+	}
+
+	f := []grider.Filter{
+		{Column: "Sum", Op: grider.FilterBetween, Values: []string{"5", "20"}},
+		{Column: "Date", Op: grider.FilterBetween, Values: []string{"2024-03-01", "2024-03-31"}},
+	}
+	if err := g.ValidateFilters(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.FilterRows(f...); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Rows) != 1 || g.Rows[0][0] != "new" {
+		t.Errorf("unexpected rows %v", g.Rows)
+	}
+}
+
+func TestFacets(t *testing.T) {
+
+	type order struct {
+		State string     `grid:"foptions=new:New|paid:Paid|lost:Lost"`
+		Sum   null.Float `grid:"type=number,ftype=number"`
+		City  string
+		Paid  null.Time `grid:"fmt=date"`
+	}
+
+	d := func(day int) null.Time { return null.TimeFrom(time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)) }
+	g := grider.New().ApplySliceOfStruct([]order{
+		{"new", null.FloatFrom(100), "Riga", null.Time{}},
+		{"paid", null.FloatFrom(9.5), "Riga", d(12)},
+		{"new", null.Float{}, "Oslo", null.Time{}},
+		{"new", null.FloatFrom(20), "Riga", d(3)},
+	})
+
+	err := g.ApplyFacets([]grider.Filter{
+		{Column: "City", Op: grider.FilterEq, Value: "Riga"},
+		{Column: "State", Op: grider.FilterEq, Value: "new"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Facets) != 2 {
+		t.Fatalf("unexpected facets %+v", g.Facets)
+	}
+
+	st := g.Facets[0]
+	expected := []grider.FacetValue{{"new", "New", 2}, {"paid", "Paid", 1}, {"lost", "Lost", 0}}
+	if !reflect.DeepEqual(st.Values, expected) {
+		t.Errorf("unexpected state facet %+v", st.Values)
+	}
+
+	sum := g.Facets[1]
+	if sum.Min != "20" || sum.Max != "100" || sum.Nulls != 0 {
+		t.Errorf("unexpected sum facet %+v", sum)
+	}
+
+	// null dates written as "-" are counted as nulls without source values.
+	facets, err := g.ComputeFacets(nil, "Paid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid := facets[0]; paid.Min != "03.01.2024" || paid.Max != "12.01.2024" || paid.Nulls != 2 {
+		t.Errorf("unexpected paid facet %+v", paid)
+	}
+
+	q := grider.FacetQuery{Column: "State", Expr: "o.state"}
+	if s := q.SQL("orders o", "o.city = $1"); s != "SELECT o.state, COUNT(*) FROM orders o WHERE o.city = $1 GROUP BY o.state ORDER BY COUNT(*) DESC, o.state" {
+		t.Errorf("unexpected sql %s", s)
+	}
+}
+
+func TestSearch(t *testing.T) {
+
+	type customer struct {
+		Name  string
+		City  string
+		Notes string `grid:"search=false"`
+		Code  string `grid:"hidden=true"`
+	}
+
+	g := grider.New().ApplySliceOfStruct([]customer{
+		{"Zoë Café", "Kraków", "cafe", "cafe"},
+		{"Anna", "Cafeville", "", ""},
+		{"Boris", "Oslo", "zoe cafe", ""},
+	})
+	g.RowIDs = []int{1, 2, 3}
+
+	found := g.Search("CAFE krakow")
+	if len(found) != 1 || found[0].Row != 0 {
+		t.Fatalf("unexpected result %+v", found)
+	}
+
+	expected := []grider.Highlight{
+		{Column: 0, Ranges: [][2]int{{4, 8}}},
+		{Column: 1, Ranges: [][2]int{{0, 6}}},
+	}
+	if !reflect.DeepEqual(found[0].Highlights, expected) {
+		t.Errorf("unexpected highlights %+v", found[0].Highlights)
+	}
+
+	g.ApplySearch("cafe")
+	if len(g.Rows) != 2 || g.RowIDs[1] != 2 || len(g.RowHighlights) != 2 {
+		t.Errorf("unexpected rows %v %v %v", g.Rows, g.RowIDs, g.RowHighlights)
+	}
+
+	g.DeleteColumns([]string{"Name"})
+	if len(g.RowHighlights[0]) != 0 || g.RowHighlights[1][0].Column != 0 {
+		t.Errorf("highlights not remapped %+v", g.RowHighlights)
+	}
+}
+
+func TestSortRows(t *testing.T) {
+
+	type invoice struct {
+		Number string `grid:"sort=natural"`
+		Client string
+		Date   null.Time `grid:"fmt=date,sortable=true"`
+		Sum    null.Float
+		Issued time.Time
+		Items  null.Int
+	}
+
+	f := null.FloatFrom
+	d := func(day int) null.Time { return null.TimeFrom(time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)) }
+	y := func(year, month int) time.Time { return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC) }
+
+	src := []invoice{
+		{"INV-10", "Marek", d(2), f(100), y(2022, 1), null.IntFrom(10)},
+		{"INV-9", "Łukasz", d(10), null.Float{}, y(2021, 12), null.IntFrom(9)},
+		{"INV-100", "Lucyna", null.Time{}, f(9.5), y(2020, 1), null.Int{}},
+	}
+
+	cases := []struct {
+		key      grider.SortKey
+		locale   string
+		expected string
+	}{
+		{grider.SortKey{Column: "Number"}, "", "INV-9,INV-10,INV-100"},
+		{grider.SortKey{Column: "Client"}, "pl", "INV-100,INV-9,INV-10"},
+		{grider.SortKey{Column: "Date"}, "", "INV-10,INV-9,INV-100"},
+		{grider.SortKey{Column: "Date", Nulls: grider.NullsFirst}, "", "INV-100,INV-10,INV-9"},
+		{grider.SortKey{Column: "Sum", Desc: true}, "", "INV-9,INV-10,INV-100"},
+		{grider.SortKey{Column: "Sum", Desc: true, Nulls: grider.NullsLast}, "", "INV-10,INV-100,INV-9"},
+		{grider.SortKey{Column: "Date", Nulls: grider.NullsLast}, "", "INV-10,INV-9,INV-100"},
+		{grider.SortKey{Column: "Issued"}, "", "INV-100,INV-9,INV-10"},
+		{grider.SortKey{Column: "Items"}, "", "INV-9,INV-10,INV-100"},
+	}
+
+	// cells are parsed by the types inferred from the attributes
+	// without source values.
+	for _, sv := range []bool{true, false} {
+		for _, c := range cases {
+			cfg := grider.DefaultConfig()
+			cfg.Locale = c.locale
+
+			opts := []func(*grider.Option){grider.WithConfig(cfg)}
+			if sv {
+				opts = append(opts, grider.WithSourceValues())
+			}
+			g := grider.New(opts...).ApplySliceOfStruct(src)
+			if err := g.SortRows(c.key); err != nil {
+				t.Fatal(err)
+			}
+
+			numbers := []string{}
+			for _, row := range g.Rows {
+				numbers = append(numbers, row[0])
+			}
+			if s := strings.Join(numbers, ","); s != c.expected {
+				t.Errorf("sort by %+v (source values %v): expected %s, got %s", c.key, sv, c.expected, s)
+			}
+		}
+	}
+
+	g := grider.New().ApplySliceOfStruct(src)
+	for _, name := range []string{"Number", "Date", "Sum", "Issued", "Items"} {
+		expected := map[string]string{"Date": "date", "Issued": "date", "Sum": "number", "Items": "number"}[name]
+		if c := g.Columns[g.ColumnIndex(name)]; c.Type != expected {
+			t.Errorf("column %s: expected type %q, got %q", name, expected, c.Type)
+		}
+	}
+}
+
+func TestShortRows(t *testing.T) {
+
+	g := grider.Grid{
+		Columns: []grider.Column{{Name: "Number"}, {Name: "Sum", Type: "number", Filter: &grider.FilterSpec{Type: grider.FilterNumber}}},
+		Rows:    [][]string{{"INV-1", "20"}, {"INV-2"}, {"INV-3", "10"}},
+	}
+
+	if err := g.SortRows(grider.SortKey{Column: "Sum"}); err != nil {
+		t.Fatal(err)
+	}
+	if g.Rows[0][0] != "INV-3" || g.Rows[2][0] != "INV-2" {
+		t.Errorf("unexpected order %q", g.Rows)
+	}
+
+	facets, err := g.ComputeFacets(nil, "Sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facets) != 1 || facets[0].Min != "10" || facets[0].Max != "20" || facets[0].Nulls != 1 {
+		t.Errorf("unexpected facets %+v", facets)
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+
+	type event struct {
+		ID   int
+		Kind string
+	}
+
+	cfg := grider.DefaultConfig()
+	cfg.CursorSecret = []byte("secret")
+	keys := []grider.SortKey{{Column: "Kind", Desc: true}, {Column: "ID"}}
+
+	// the first page read with limit 3 for the page size 2.
+	g := grider.New(grider.WithConfig(cfg), grider.WithName("audit"), grider.WithSourceValues()).
+		ApplySliceOfStruct([]event{{7, "login"}, {9, "login"}, {3, "edit"}})
+	if err := g.SetCursorPage(nil, keys, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pi := g.PageInfo
+	if len(g.Rows) != 2 || !pi.HasNext || pi.HasPrev || pi.NextCursor == "" || pi.PrevCursor != "" {
+		t.Fatalf("unexpected page %v %+v", g.Rows, pi)
+	}
+
+	c, err := grider.DecodeCursor(cfg.CursorSecret, pi.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Check("audit", keys); err != nil {
+		t.Fatal(err)
+	}
+
+	exprs := map[string]string{"Kind": "e.kind", "ID": "e.id"}
+	where, args, err := c.Where(exprs, grider.PlaceholderDollar, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != "(e.kind < $2) OR (e.kind = $2 AND e.id > $3)" || len(args) != 2 || args[0] != "login" || args[1] != int64(9) {
+		t.Errorf("unexpected where %s %v", where, args)
+	}
+
+	c.Backward = true
+	where, args, _ = c.Where(exprs, grider.PlaceholderQuestion, 0)
+	if where != "(e.kind > ?) OR (e.kind = ? AND e.id < ?)" || len(args) != 3 {
+		t.Errorf("unexpected backward where %s %v", where, args)
+	}
+	if ob, _ := c.OrderBy(exprs); ob != "ORDER BY e.kind, e.id DESC" {
+		t.Errorf("unexpected order by %s", ob)
+	}
+
+	tampered := pi.NextCursor[:len(pi.NextCursor)-2] + "AA"
+	if _, err := grider.DecodeCursor(cfg.CursorSecret, tampered); err != grider.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if _, err := grider.DecodeCursor([]byte("other"), pi.NextCursor); err != grider.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	buf, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), `"paginationType":"cursor","pageInfo":{"hasNext":true,"hasPrev":false`) {
+		t.Errorf("unexpected json %s", buf)
+	}
+
+	fc := grider.Cursor{Keys: []grider.SortKey{{Column: "Sum"}}, Values: []interface{}{json.Number("9.5")}}
+	if _, args, err := fc.Where(map[string]string{"Sum": "o.sum"}, grider.PlaceholderDollar, 0); err != nil || args[0] != 9.5 {
+		t.Errorf("unexpected float argument %v %v", args, err)
+	}
+
+	// cursor values aren't taken from formatted cells.
+	g = grider.New(grider.WithConfig(cfg), grider.WithName("audit")).
+		ApplySliceOfStruct([]event{{7, "login"}, {9, "login"}, {3, "edit"}})
+	if err := g.SetCursorPage(nil, keys, 2, nil); err == nil || len(g.Rows) != 3 {
+		t.Errorf("expected error without source values, got %v", err)
+	}
+}
+
+func TestPaging(t *testing.T) {
+
+	type row struct {
+		ID int
+	}
+
+	src := make([]row, 45)
+	for i := range src {
+		src[i].ID = i + 1
+	}
+
+	g := grider.New().ApplyPage(src, 3, 20, 10, 20, 50)
+	p := g.Paging
+	if p == nil || p.Page != 3 || p.TotalPages != 3 || p.TotalRows != 45 || len(g.Rows) != 5 || g.Rows[0][0] != "41" {
+		t.Fatalf("unexpected page %+v %v", p, g.Rows)
+	}
+
+	g = grider.New().ApplyPage(src, 9, 15, 10, 20)
+	if g.Paging.PageSize != 10 || g.Paging.Page != 5 || len(g.Rows) != 5 {
+		t.Errorf("unexpected page %+v", g.Paging)
+	}
+
+	g = grider.New().ApplyPage([]row{}, 1, 10)
+	if g.Paging.TotalPages != 1 || len(g.Rows) != 0 || len(g.Columns) != 1 {
+		t.Errorf("unexpected empty page %+v", g.Paging)
+	}
+
+	g.SetPaginationType(grider.PaginationWithout)
+	if !g.NoPagination {
+		t.Error("NoPagination expected to be true")
+	}
+	g.Paging = nil
+	if err := g.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// NoPagination follows PaginationType in JSON.
+	g.NoPagination = false
+	if buf, _ := json.Marshal(g); !bytes.Contains(buf, []byte(`"noPagination":true,"paginationType":"without"`)) {
+		t.Errorf("unexpected pagination %s", buf)
+	}
+
+	g.PaginationType = grider.PaginationClient
+	g.NoPagination = true
+	if buf, _ := json.Marshal(g); !bytes.Contains(buf, []byte(`"isFilterable":false,"paginationType":"client"`)) {
+		t.Errorf("unexpected pagination %s", buf)
+	}
+
+	legacy := grider.Grid{NoPagination: true}
+	if buf, _ := json.Marshal(legacy); !bytes.Contains(buf, []byte(`"noPagination":true,"paginationType":"without"`)) {
+		t.Errorf("unexpected legacy pagination %s", buf)
+	}
+}
+
+func TestEncodings(t *testing.T) {
+
+	g := grider.New()
+	g.Columns = []grider.Column{{Name: "ID"}, {Name: "State", Width: 120}}
+	g.Rows = [][]string{{"1", "New"}, {"2", "New"}, {"3", "Paid"}, {"4", "New"}}
+	g.RowIDs = []int{1, 2, 3, 4}
+	g.SetPaginationType(grider.PaginationClient)
+
+	expected, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []grider.Encoding{grider.EncodingJSON, grider.EncodingColumnar, grider.EncodingDict, grider.EncodingMsgPack} {
+		var buf bytes.Buffer
+		if err := g.Encode(&buf, e); err != nil {
+			t.Fatal(e, err)
+		}
+
+		d, err := grider.DecodeGrid(&buf, e)
+		if err != nil {
+			t.Fatal(e, err)
+		}
+
+		got, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(e, err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: expected %s, got %s", e, expected, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := g.Encode(&buf, grider.EncodingDict); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"data":[{"values":["1","2","3","4"]},{"dict":["New","Paid"],"index":[0,0,1,0]}]`) {
+		t.Errorf("unexpected dict encoding %s", buf.String())
+	}
+	if strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("dict encoding ends by newline")
+	}
+
+	// rows without columns, empty and null rows survive all encodings.
+	for _, rows := range [][][]string{{{}, {}}, {}, nil} {
+		g := grider.Grid{Rows: rows}
+		expected, _ := json.Marshal(&g)
+		for _, e := range []grider.Encoding{grider.EncodingJSON, grider.EncodingColumnar, grider.EncodingDict, grider.EncodingMsgPack} {
+			var buf bytes.Buffer
+			if err := g.Encode(&buf, e); err != nil {
+				t.Fatal(e, err)
+			}
+			d, err := grider.DecodeGrid(&buf, e)
+			if err != nil {
+				t.Fatal(e, err)
+			}
+			if got, _ := json.Marshal(d); !bytes.Equal(got, expected) || (d.Rows == nil) != (rows == nil) {
+				t.Errorf("%s: expected %s, got %s", e, expected, got)
+			}
+		}
+	}
+
+	// deeply nested msgpack is rejected.
+	nested := append(bytes.Repeat([]byte{0x91}, 100), 0xc0)
+	if _, err := grider.DecodeGrid(bytes.NewReader(nested), grider.EncodingMsgPack); err == nil || !strings.Contains(err.Error(), "nesting") {
+		t.Errorf("expected nesting error, got %v", err)
+	}
+
+	for _, src := range []string{
+		`{"columns":[{"name":"A"},{"name":"B"}],"encoding":"columnar","rowCount":1,"data":[[],["x"]]}`,
+		`{"columns":[{"name":"A"}],"encoding":"columnar","data":[["x"]]}`,
+	} {
+		if _, err := grider.DecodeGrid(strings.NewReader(src), grider.EncodingColumnar); err == nil {
+			t.Errorf("malformed grid decoded %s", src)
+		}
+	}
+}
+
+// benchmarkGrid returns the grid of n rows with low cardinality
+// status and date columns.
+func benchmarkGrid(n int) *grider.Grid {
+	states := []string{"New", "In progress", "Waiting for approval", "Paid", "Cancelled"}
+
+	g := grider.New()
+	g.Columns = []grider.Column{{Name: "ID"}, {Name: "Customer"}, {Name: "State"}, {Name: "Date"}, {Name: "Sum"}}
+	for i := 0; i < n; i++ {
+		g.Rows = append(g.Rows, []string{
+			strconv.Itoa(i + 1),
+			"Customer " + strconv.Itoa(i%700),
+			states[i%len(states)],
+			"0" + strconv.Itoa(1+i%9) + ".03.2024",
+			strconv.Itoa(i*7%10000) + ".50",
+		})
+	}
+	return g
+}
+
+func BenchmarkEncode(b *testing.B) {
+	g := benchmarkGrid(10000)
+
+	for _, e := range []grider.Encoding{grider.EncodingJSON, grider.EncodingColumnar, grider.EncodingDict, grider.EncodingMsgPack} {
+		b.Run(string(e), func(b *testing.B) {
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := g.Encode(&buf, e); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(buf.Len()), "bytes/grid")
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	g := benchmarkGrid(10000)
+
+	for _, e := range []grider.Encoding{grider.EncodingJSON, grider.EncodingColumnar, grider.EncodingDict, grider.EncodingMsgPack} {
+		var buf bytes.Buffer
+		if err := g.Encode(&buf, e); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(string(e), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := grider.DecodeGrid(bytes.NewReader(buf.Bytes()), e); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type streamOrder struct {
+	ID    int
+	State string `grid:"order=1"`
+	Sum   float64
+}
+
+func (o *streamOrder) Object() interface{} {
+	return map[string]int{"id": o.ID}
+}
+
+func TestStreamJSON(t *testing.T) {
+
+	orders := []streamOrder{{1, "New", 10}, {2, "Paid", 20.5}, {3, "New", 0}}
+
+	g := grider.New().ApplySliceOfStruct(orders)
+	g.RowIDs = []int{1, 2, 3}
+	g.RowActions = [][]grider.ActionCode{{"edit"}, nil, {"edit", "delete"}}
+	g.ApplySearch("new")
+
+	expected, err := g.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.StreamJSON(&buf, g.Iter()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(expected) {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+
+	// rows converted while they are received.
+	g = grider.New().ApplySliceOfStruct(orders)
+	expected, err = g.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := grider.New().ApplySliceOfStruct([]streamOrder{})
+	rows, errc := make(chan grider.StreamRow), make(chan error, 1)
+	go func() {
+		defer close(rows)
+		for i := range orders {
+			rows <- s.StructRow(orders[i])
+		}
+		errc <- nil
+	}()
+
+	buf.Reset()
+	if err := s.StreamJSON(&buf, grider.ChanRows(context.Background(), rows, errc)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(expected) {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+
+	// the producer error is returned.
+	rows, errc = make(chan grider.StreamRow), make(chan error, 1)
+	close(rows)
+	errc <- errors.New("connection lost")
+	if err := s.StreamJSON(ioutil.Discard, grider.ChanRows(context.Background(), rows, errc)); err == nil || err.Error() != "connection lost" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the producer stops when the stream is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, errc = make(chan grider.StreamRow), make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			select {
+			case rows <- s.StructRow(orders[i%len(orders)]):
+				if i == 1 {
+					cancel()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	if err := s.StreamJSON(ioutil.Discard, grider.ChanRows(ctx, rows, errc)); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	<-stopped
+
+	// no rows and row arrays of zero values.
+	for _, g := range []*grider.Grid{
+		grider.New().ApplySliceOfStruct([]streamOrder{}),
+		{Columns: g.Columns, Rows: [][]string{}},
+		{
+			Columns:    g.Columns,
+			Rows:       g.Rows,
+			RowIDs:     make([]int, len(g.Rows)),
+			RowUIDs:    make([]uuid.UUID, len(g.Rows)),
+			RowActions: make([][]grider.ActionCode, len(g.Rows)),
+		},
+	} {
+		expected, err := g.JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		if err := g.StreamJSON(&buf, g.Iter()); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != string(expected) {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+	}
+
+	rows = make(chan grider.StreamRow)
+	close(rows)
+	buf.Reset()
+	if err := s.StreamJSON(&buf, grider.ChanRows(context.Background(), rows, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"rows":null`) {
+		t.Errorf("expected null rows, got %s", buf.String())
+	}
+
+	// columns are validated in debug mode.
+	cfg := grider.DefaultConfig()
+	cfg.Debug = true
+	bad := grider.New(grider.WithConfig(cfg))
+	bad.Columns = []grider.Column{{Name: "ID"}, {Name: "ID"}, {Name: "Link", Type: "link", Href: "/orders/{Missing}"}}
+	bad.Rows = [][]string{{"1", "1", "x"}}
+	if _, err := bad.JSON(); err == nil {
+		t.Fatal("invalid grid expected to fail JSON")
+	}
+	buf.Reset()
+	if err := bad.StreamJSON(&buf, bad.Iter()); err == nil || buf.Len() != 0 {
+		t.Errorf("invalid grid expected to fail StreamJSON, got %v %s", err, buf.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	type order struct {
+		ID    int
+		State string `grid:"title=Статус"`
+		Note  string `grid:"hidden=true"`
+	}
+
+	orders := make([]order, 100)
+	for i := range orders {
+		orders[i] = order{ID: i + 1, State: "New <draft>", Note: "note"}
+	}
+
+	h := &grider.Handler{
+		Grid: func(r *http.Request) (*grider.Grid, error) {
+			return grider.New().ApplySliceOfStruct(orders), nil
+		},
+		FileName: "Заказы 2024",
+	}
+
+	get := func(target string, hdr map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range hdr {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/orders", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || get("/orders", nil).Header().Get("ETag") != etag {
+		t.Errorf("ETag expected to be stable, got %q", etag)
+	}
+
+	w = get("/orders?format=csv", nil)
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="______ 2024.csv"; filename*=UTF-8''%D0%97%D0%B0%D0%BA%D0%B0%D0%B7%D1%8B%202024.csv` {
+		t.Errorf("unexpected Content-Disposition %s", cd)
+	}
+	if !strings.HasPrefix(w.Body.String(), "ID,Статус\n1,New <draft>\n") {
+		t.Errorf("unexpected CSV %q", w.Body.String()[:40])
+	}
+
+	w = get("/orders", map[string]string{"Accept": "text/html;q=0.5, application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"})
+	if w.Header().Get("Content-Type") != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" ||
+		!strings.HasPrefix(w.Body.String(), "PK") || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("unexpected XLSX response %v", w.Header())
+	}
+
+	w = get("/orders", map[string]string{"Accept": "text/html", "Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("gzip expected, got %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "<tr><th>ID</th><th>Статус</th></tr>") ||
+		!strings.Contains(string(body), "<td>New &lt;draft&gt;</td>") {
+		t.Errorf("unexpected HTML %s", body[:300])
+	}
+
+	w = get("/orders", map[string]string{"Accept": "application/x-msgpack"})
+	if w.Header().Get("Content-Type") != "application/x-msgpack" {
+		t.Errorf("unexpected response %v", w.Header())
+	}
+	if g, err := grider.DecodeGrid(w.Body, grider.EncodingMsgPack); err != nil || len(g.Rows) != len(orders) {
+		t.Errorf("unexpected msgpack grid %v", err)
+	}
+
+	if w := get("/orders", map[string]string{"Accept": "image/png"}); w.Code != http.StatusNotAcceptable {
+		t.Errorf("expected status 406, got %d", w.Code)
+	}
+	if w := get("/orders?format=pdf", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+
+	// streamed CSV is compressed, short one is sent with the length.
+	h.ExcelOptions = []func(*grider.ExcelOption){grider.WithBOM(true)}
+	w = get("/orders?format=csv", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+		t.Fatalf("gzip expected, got %v", w.Header())
+	}
+	if zr, err = gzip.NewReader(w.Body); err != nil {
+		t.Fatal(err)
+	}
+	if body, err = ioutil.ReadAll(zr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), "\ufeffID,Статус\n1,New <draft>\n") || !strings.HasSuffix(string(body), "100,New <draft>\n") {
+		t.Errorf("unexpected CSV %q", body[:40])
+	}
+
+	orders = orders[:1]
+	w = get("/orders?format=csv", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) ||
+		w.Body.String() != "\ufeffID,Статус\n1,New <draft>\n" {
+		t.Errorf("unexpected CSV %v %q", w.Header(), w.Body.String())
+	}
+
+	// export errors are written before the response is sent.
+	h.ExcelOptions = []func(*grider.ExcelOption){grider.WithSortKeys(grider.SortKey{Column: "Missing"})}
+	if w := get("/orders?format=csv", nil); w.Code != http.StatusInternalServerError || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("expected status 500, got %d %v", w.Code, w.Header())
+	}
+
+	// the response is aborted if the client fails.
+	h.ExcelOptions = nil
+	func() {
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Errorf("expected abort, got %v", err)
+			}
+		}()
+		r := httptest.NewRequest(http.MethodGet, "/orders?format=html", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		h.ServeHTTP(failingWriter{httptest.NewRecorder()}, r)
+	}()
+}
+
+// failingWriter is the response writer of the gone client.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestExportPinnedColumns(t *testing.T) {
+
+	g := grider.New()
+	g.Columns = []grider.Column{{Name: "Sum", Pin: "right"}, {Name: "State"}, {Name: "ID", Pin: "left"}}
+	g.Rows = [][]string{{"10", "New", "1"}}
+
+	var buf bytes.Buffer
+	if err := g.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ID,State,Sum\n1,New,10\n" {
+		t.Errorf("unexpected CSV %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<tr><th>ID</th><th>State</th><th>Sum</th></tr>") ||
+		!strings.Contains(buf.String(), "<tr><td>1</td><td>New</td><td>10</td></tr>") {
+		t.Errorf("unexpected HTML %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.WriteExcel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != "ID,State,Sum" {
+		t.Errorf("unexpected XLSX rows %q", rows)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+
+	newGrid := func(codes ...grider.ActionCode) *grider.Grid {
+		g := grider.New()
+		g.Columns = []grider.Column{{Name: "Name"}}
+		g.Rows = [][]string{{"Robert"}}
+		g.Action = grider.NewActionSet()
+		for _, c := range codes {
+			g.Action[c] = grider.Action{Code: c, Title: string(c)}
+		}
+		return g
+	}
+
+	// codes of ActionSet are written in sorted order.
+	buf, err := json.Marshal(newGrid("Edit", "Delete", "Copy", "Archive", "View").Action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	if _, err := dec.Token(); err != nil {
+		t.Fatal(err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, tok.(string))
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(codes, []string{"Archive", "Copy", "Delete", "Edit", "View"}) {
+		t.Errorf("unexpected order of action codes %v", codes)
+	}
+
+	a, err := newGrid("Edit", "Delete", "Copy").Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newGrid("Copy", "Edit", "Delete").Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("hashes of equal grids differ: %s %s", a, b)
+	}
+
+	g := newGrid("Edit")
+	buf, err = g.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf)
+	if h, _ := g.Hash(); h != hex.EncodeToString(sum[:]) {
+		t.Errorf("hash expected to be SHA-256 of JSON, got %s", h)
+	}
+
+	g.Rows[0][0] = "Boris"
+	if c, _ := g.Hash(); c == a {
+		t.Error("hash not changed")
+	}
+
+	p := grider.Page{Widgets: []grider.Widgeter{grider.GridWidget{Grid: newGrid("Edit", "Delete")}}}
+	q := grider.Page{Widgets: []grider.Widgeter{grider.GridWidget{Grid: newGrid("Delete", "Edit")}}}
+	if ph, _ := p.Hash(); ph == "" {
+		t.Error("empty page hash")
+	} else if qh, _ := q.Hash(); ph != qh {
+		t.Errorf("hashes of equal pages differ: %s %s", ph, qh)
+	}
+}
+
+func TestHandlerCache(t *testing.T) {
+
+	calls := 0
+	rows := [][]string{{"1"}, {"2"}}
+	h := &grider.Handler{
+		Grid: func(r *http.Request) (*grider.Grid, error) {
+			calls++
+			g := grider.New()
+			g.Columns = []grider.Column{{Name: "ID"}}
+			g.Rows = rows
+			return g, nil
+		},
+		Cache: grider.NewCache(time.Minute),
+		Tags:  []string{"orders"},
+	}
+
+	get := func(target, inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/orders?b=2&a=1", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	// the query is normalized.
+	if w := get("/orders?a=1&b=2", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 || calls != 1 {
+		t.Errorf("expected cached 304, got %d, %d calls", w.Code, calls)
+	}
+	if w := get("/orders?a=1&b=2&format=csv", etag); w.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected 200, got %d, %d calls", w.Code, calls)
+	}
+	if h.Cache.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", h.Cache.Len())
+	}
+
+	rows = [][]string{{"1"}}
+	h.Cache.Invalidate("customers")
+	if w := get("/orders?a=1&b=2", etag); w.Code != http.StatusNotModified || calls != 2 {
+		t.Errorf("expected cached 304, got %d, %d calls", w.Code, calls)
+	}
+
+	h.Cache.Invalidate("orders")
+	w = get("/orders?a=1&b=2", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag || calls != 3 {
+		t.Errorf("expected new content, got %d %v, %d calls", w.Code, w.Header(), calls)
+	}
+
+	// not modified grid isn't rendered without cache.
+	h.Cache = nil
+	etag = w.Header().Get("ETag")
+	if w := get("/orders", etag); w.Code != http.StatusNotModified || calls != 4 {
+		t.Errorf("expected 304, got %d", w.Code)
+	}
+
+	h.Cache = grider.NewCache(10 * time.Millisecond)
+	get("/orders", "")
+	time.Sleep(20 * time.Millisecond)
+	get("/orders", "")
+	if calls != 6 {
+		t.Errorf("expired entry expected to be rendered again, %d calls", calls)
+	}
+
+	// least recently used entries are evicted.
+	h.Cache = grider.NewCache(0)
+	size := len(get("/orders?a=1", "").Body.Bytes())
+	h.Cache.SetMaxBytes(2 * size)
+	get("/orders?a=2", "")
+	get("/orders?a=1", "")
+	get("/orders?a=3", "")
+	if h.Cache.Len() != 2 || h.Cache.Size() != 2*size || calls != 9 {
+		t.Errorf("expected 2 entries of %d bytes, got %d of %d, %d calls", size, h.Cache.Len(), h.Cache.Size(), calls)
+	}
+	if get("/orders?a=1", ""); calls != 9 {
+		t.Errorf("recently used entry expected to be kept, %d calls", calls)
+	}
+	if get("/orders?a=2", ""); calls != 10 {
+		t.Errorf("least recently used entry expected to be evicted, %d calls", calls)
+	}
+
+	h.Cache.SetMaxBytes(size - 1)
+	get("/orders?a=4", "")
+	if h.Cache.Len() != 0 || h.Cache.Size() != 0 {
+		t.Errorf("expected empty cache, got %d entries", h.Cache.Len())
+	}
+}
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func TestGenerateProtocol(t *testing.T) {
+
+	tests := []struct {
+		golden string
+		gen    func(io.Writer) error
+	}{
+		{"testdata/grider.d.ts", grider.GenerateTypeScript},
+		{"testdata/grider.schema.json", grider.GenerateJSONSchema},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.gen(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if *update {
+			if err := ioutil.WriteFile(tt.golden, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		expected, err := ioutil.ReadFile(tt.golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("%s is outdated, run go test -run TestGenerateProtocol -update", tt.golden)
+		}
+	}
+}
+
+// openWorkbook opens the workbook of the download response.
+func openWorkbook(t *testing.T, resp *grider.DownloadResponse) *excelize.File {
+	t.Helper()
+
+	buf, err := base64.StdEncoding.DecodeString(resp.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// cellValue returns the value of the cell or fails the test.
+func cellValue(t *testing.T, f *excelize.File, sheet, cell string) string {
+	t.Helper()
+
+	v, err := f.GetCellValue(sheet, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestPageExcelize(t *testing.T) {
+
+	grid := func() *grider.Grid {
+		return &grider.Grid{Columns: []grider.Column{{Name: "ID"}}, Rows: [][]string{{"1"}}}
+	}
+	titled := func(title string) *grider.Widget {
+		return &grider.Widget{Header: &grider.Header{Title: title}}
+	}
+
+	const long = "Very long widget title exceeding the limit"
+
+	p := grider.Page{
+		Header: &grider.Header{Title: "Order 15"},
+		Widgets: []grider.Widgeter{
+			grider.AttrValueWidget{Widget: titled("Customer"), Lines: []grider.Line{{Label: "Name", Value: "Robert"}}},
+			&grider.ContentWidget{Body: "line1\nline2"},
+			grider.GridWidget{Widget: titled("Items: 2024/Q1*"), Grid: grid()},
+			grider.GridWidget{Widget: titled(long), Grid: grid()},
+			&grider.GridWidget{Widget: titled(long), Grid: grid()},
+		},
+		Tabs: []grider.Tab{
+			{Header: &grider.Header{Title: "Summary"}, Widgets: []grider.Widgeter{grider.GridWidget{Grid: grid()}}},
+		},
+	}
+
+	resp, err := p.Excelize("order.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := openWorkbook(t, resp)
+
+	expected := []string{
+		"Summary",
+		"Notes",
+		"Items 2024Q1",
+		"Very long widget title exceedin",
+		"Very long widget title exce (2)",
+		"Summary (2)",
+	}
+	if sheets := f.GetSheetList(); !reflect.DeepEqual(sheets, expected) {
+		t.Errorf("expected sheets %q, got %q", expected, sheets)
+	}
+
+	cells := map[string]string{
+		"Summary!A1":      "Customer",
+		"Summary!A2":      "Name",
+		"Summary!B2":      "Robert",
+		"Notes!A1":        "Order 15",
+		"Notes!A2":        "line1",
+		"Notes!A3":        "line2",
+		"Items 2024Q1!A2": "1",
+		"Summary (2)!A1":  "ID",
+	}
+	for ref, v := range cells {
+		i := strings.LastIndexByte(ref, '!')
+		if got := cellValue(t, f, ref[:i], ref[i+1:]); got != v {
+			t.Errorf("%s: expected %q, got %q", ref, v, got)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := map[string]string{
+		"Orders [2024]: Q1/Q2": "Orders 2024 Q1Q2",
+		`a*b?c\d`:              "abcd",
+		"'quoted'":             "quoted",
+		"Заказы клиента с очень длинным названием": "Заказы клиента с очень длинным",
+		" ?* ": "",
+	}
+	for s, expected := range tests {
+		if got := grider.SheetName(s); got != expected {
+			t.Errorf("SheetName(%q): expected %q, got %q", s, expected, got)
+		}
+	}
+}
+
+// sheetXML returns XML of the worksheet file name of the workbook.
+func sheetXML(t *testing.T, resp *grider.DownloadResponse, name string) string {
+	t.Helper()
+
+	buf, err := base64.StdEncoding.DecodeString(resp.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		xml, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(xml)
+	}
+	t.Fatalf("no %s in the workbook", name)
+	return ""
+}
+
+func TestExcelizeOptions(t *testing.T) {
+
+	g := grider.Grid{
+		Columns: []grider.Column{
+			{Name: "ID", Pin: "left", Width: 70},
+			{Name: "Name", MinWidth: 140},
+			{Name: "Status"},
+		},
+		Rows: [][]string{{"1", "Robert", "New"}, {"2", "Anna", "Paid"}},
+	}
+
+	resp, err := g.Excelize("orders.xlsx",
+		grider.WithSheetTitle("Orders", "May"),
+		grider.WithMetadataSheet("Status = Paid"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := openWorkbook(t, resp)
+
+	merges, err := f.GetMergeCells("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	for _, m := range merges {
+		refs = append(refs, m.GetStartAxis()+":"+m.GetEndAxis())
+	}
+	if !reflect.DeepEqual(refs, []string{"A1:C1", "A2:C2"}) {
+		t.Errorf("unexpected merged cells %v", refs)
+	}
+	if v := cellValue(t, f, "Sheet1", "A1"); v != "Orders" {
+		t.Errorf("unexpected title %q", v)
+	}
+	if v := cellValue(t, f, "Sheet1", "C4"); v != "Status" {
+		t.Errorf("header expected in the row 4, got %q", v)
+	}
+
+	// title, subtitle and the empty row are above the header.
+	xml := sheetXML(t, resp, "xl/worksheets/sheet1.xml")
+	for _, s := range []string{`xSplit="1"`, `ySplit="4"`, `topLeftCell="B5"`, `state="frozen"`, `<autoFilter ref="$A$4:$C$6"`} {
+		if !strings.Contains(xml, s) {
+			t.Errorf("%s not found in the sheet %s", s, xml)
+		}
+	}
+
+	widths := map[string]float64{"A": 10, "B": 20, "C": 8}
+	for col, expected := range widths {
+		w, err := f.GetColWidth("Sheet1", col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w != expected {
+			t.Errorf("column %s: expected width %v, got %v", col, expected, w)
+		}
+	}
+
+	if v := cellValue(t, f, grider.MetadataSheetName, "B2"); v != "Status = Paid" {
+		t.Errorf("unexpected filter description %q", v)
+	}
+
+	// the page widget titled "Info" doesn't replace the metadata sheet.
+	p := grider.Page{Widgets: []grider.Widgeter{
+		grider.GridWidget{Widget: &grider.Widget{Header: &grider.Header{Title: "info"}}, Grid: &g},
+	}}
+	resp, err = p.Excelize("page.xlsx", grider.WithMetadataSheet(), grider.WithFrozenHeader(false), grider.WithAutoFilter(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = openWorkbook(t, resp)
+	if sheets := f.GetSheetList(); !reflect.DeepEqual(sheets, []string{"info (2)", "Info"}) {
+		t.Errorf("unexpected sheets %q", sheets)
+	}
+	if v := cellValue(t, f, "info (2)", "A1"); v != "ID" {
+		t.Errorf("unexpected header %q", v)
+	}
+
+	xml = sheetXML(t, resp, "xl/worksheets/sheet1.xml")
+	if strings.Contains(xml, "<pane") || strings.Contains(xml, "<autoFilter") {
+		t.Errorf("panes and autofilter expected to be off %s", xml)
+	}
+}
+
+// fakeDriver serves result sets of fakeResults by the query text.
+type fakeDriver struct{}
+
+type fakeResult struct {
+	columns []string
+	types   []string
+	scan    []reflect.Type
+	rows    [][]driver.Value
+}
+
+var fakeResults = map[string]fakeResult{}
+
+func init() {
+	sql.Register("grider-fake", fakeDriver{})
+}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt string
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("not supported") }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	res, ok := fakeResults[string(s)]
+	if !ok {
+		return nil, errors.New("unknown query " + string(s))
+	}
+	return &fakeRows{res: res}, nil
+}
+
+type fakeRows struct {
+	res fakeResult
+	pos int
+}
+
+func (r *fakeRows) Columns() []string { return r.res.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos == len(r.res.rows) {
+		return io.EOF
+	}
+	copy(dest, r.res.rows[r.pos])
+	r.pos++
+	return nil
+}
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string { return r.res.types[i] }
+func (r *fakeRows) ColumnTypeScanType(i int) reflect.Type   { return r.res.scan[i] }
+
+func TestApplySQLRows(t *testing.T) {
+
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	fakeResults["orders"] = fakeResult{
+		columns: []string{"id", "amount", "created", "shipped", "name"},
+		types:   []string{"INT8", "NUMERIC", "DATE", "DATE", "TEXT"},
+		scan: []reflect.Type{
+			reflect.TypeOf(int64(0)),
+			reflect.TypeOf([]byte(nil)),
+			reflect.TypeOf(time.Time{}),
+			reflect.TypeOf(sql.RawBytes(nil)), // MySQL without parseTime
+			reflect.TypeOf(""),
+		},
+		rows: [][]driver.Value{
+			{int64(1), []byte("12345678901234567.89"), created, []byte("2024-05-02"), "Robert"},
+			{int64(2), nil, nil, nil, nil},
+			{int64(3), []byte("9.5"), created, []byte("2024-05-03"), "Anna"},
+		},
+	}
+
+	db, err := sql.Open("grider-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var g grider.Grid
+	if err := g.ApplySQLRows(rows, map[string]grider.Column{"name": {Title: "Customer"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, c := range g.Columns {
+		types = append(types, c.Name+":"+c.Type+":"+c.Align)
+	}
+	expected := []string{"id:number:right", "amount:number:right", "created:date:", "shipped::", "name::"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected columns %q, got %q", expected, types)
+	}
+	if g.Columns[4].Title != "Customer" {
+		t.Errorf("unexpected title %q", g.Columns[4].Title)
+	}
+
+	rowsExpected := [][]string{
+		{"1", "12345678901234567.89", "01.05.2024", "2024-05-02", "Robert"},
+		{"2", "", "-", "", ""},
+		{"3", "9.5", "01.05.2024", "2024-05-03", "Anna"},
+	}
+	if !reflect.DeepEqual(g.Rows, rowsExpected) {
+		t.Errorf("expected rows %q, got %q", rowsExpected, g.Rows)
+	}
+
+	// decimals are compared as numbers.
+	if err := g.SortRows(grider.SortKey{Column: "amount"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := []string{g.Rows[0][0], g.Rows[1][0], g.Rows[2][0]}; !reflect.DeepEqual(ids, []string{"3", "1", "2"}) {
+		t.Errorf("unexpected order %q", ids)
+	}
+}
+
+func TestExpand(t *testing.T) {
+
+	type order struct {
+		ID     int
+		Months map[string]int `grid:"expand=columns,etitle=Sales {key}"`
+		Phones []string       `grid:"expand=columns,etitle=Phone {n}"`
+		Tags   []string       `grid:"expand=join,sep=' | '"`
+		Notes  []string       `grid:"expand=join,sep=', '"`
+		Labels []string       `grid:"expand=chips"`
+		Date   time.Time      `grid:"fmt=date"`
+	}
+
+	d := time.Date(2024, 3, 10, 15, 4, 0, 0, time.UTC)
+	g := grider.New().ApplySliceOfStruct([]order{
+		{1, map[string]int{"Feb": 2}, nil, []string{"a", "b"}, []string{"x", "y"}, []string{"new"}, d},
+		{2, map[string]int{"Jan": 1, "Mar": 3}, []string{"1", "2"}, nil, nil, nil, d},
+	})
+
+	var names, titles []string
+	for _, c := range g.Columns {
+		names = append(names, c.Name)
+		titles = append(titles, c.Title)
+	}
+	expected := []string{"ID", "MonthsFeb", "MonthsJan", "MonthsMar", "Phones1", "Phones2", "Tags", "Notes", "Labels", "Date"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected columns %q, got %q", expected, names)
+	}
+	if titles[3] != "Sales Mar" || titles[5] != "Phone 2" {
+		t.Errorf("unexpected titles %q", titles)
+	}
+	if g.Columns[8].Type != "chips" {
+		t.Errorf("unexpected chips type %q", g.Columns[8].Type)
+	}
+
+	rows := [][]string{
+		{"1", "2", "", "", "", "", "a | b", "x, y", `["new"]`, "10.03.2024"},
+		{"2", "", "1", "3", "1", "2", "", "", "[]", "10.03.2024"},
+	}
+	if !reflect.DeepEqual(g.Rows, rows) {
+		t.Errorf("expected rows %q, got %q", rows, g.Rows)
+	}
+}
@@ -0,0 +1,497 @@
+package grider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Format is the format of the grid served by Handler.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatXLSX Format = "xlsx"
+	FormatCSV  Format = "csv"
+	FormatHTML Format = "html"
+)
+
+// ContentType returns MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return xlsxContentType
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// negotiated lists media types of Accept header served by Handler
+// in order of preference.
+var negotiated = []struct {
+	media    string
+	format   Format
+	encoding Encoding
+}{
+	{"application/json", FormatJSON, EncodingJSON},
+	{"application/x-msgpack", FormatJSON, EncodingMsgPack},
+	{xlsxContentType, FormatXLSX, ""},
+	{"text/csv", FormatCSV, ""},
+	{"text/html", FormatHTML, ""},
+}
+
+// gzipMinSize is the minimal size of the response compressed by Handler.
+const gzipMinSize = 1024
+
+// GridFunc returns the grid served in response to the request.
+type GridFunc func(r *http.Request) (*Grid, error)
+
+// Handler serves the grid as JSON, XLSX, CSV or HTML. The format is
+// taken from the query parameter FormatParam or negotiated by Accept
+// header, JSON is served if Accept is empty. The encoding of JSON is
+// taken from the query parameter "enc", see ParseEncoding.
+//
+// Files are sent as is, XLSX and CSV are sent as attachments named
+// FileName with the format extension. Responses have ETag built from
+// Grid.Hash, the response isn't rendered if If-None-Match header matches
+// it. CSV and HTML are written while they are exported, JSON and XLSX
+// are rendered first. Responses are compressed by gzip if the client
+// accepts it. The response failed after it's started is aborted
+// by panic(http.ErrAbortHandler).
+type Handler struct {
+	// Grid returns the grid served by ServeHTTP.
+	Grid GridFunc
+
+	// FileName is the name of the downloaded file without extension,
+	// "grid" if empty. Non ASCII names are allowed.
+	FileName string
+
+	// FormatParam is the name of the query parameter holding Format,
+	// "format" if empty.
+	FormatParam string
+
+	// ExcelOptions are applied to XLSX, CSV and HTML export.
+	ExcelOptions []func(*ExcelOption)
+
+	// Error writes the response if Grid or export fails. By default
+	// status 500 is written.
+	Error func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Cache holds rendered grids if not nil. Entries are stored with
+	// Tags, so Cache.Invalidate(tags...) removes grids of the handler
+	// after the data is changed.
+	Cache *Cache
+	Tags  []string
+
+	// CacheKey returns the key of the grid in Cache. By default it's
+	// the path and the query of the request. The key must include the
+	// user if the grid depends on user permissions.
+	CacheKey func(r *http.Request) string
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, enc, status := h.negotiate(r)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Add("Vary", "Accept, Accept-Encoding")
+
+	var key string
+	if h.Cache != nil {
+		key = h.cacheKey(r, f, enc)
+		if rd, ok := h.Cache.get(key); ok {
+			h.write(w, r, rd)
+			return
+		}
+	}
+
+	g, err := h.Grid(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	h.serve(w, r, g, f, enc, key)
+}
+
+// Serve writes the grid g in response to r like ServeHTTP does. It's
+// used by endpoints building the grid themselves. Grid and Cache fields
+// of h aren't used.
+func (h *Handler) Serve(w http.ResponseWriter, r *http.Request, g *Grid) {
+	f, enc, status := h.negotiate(r)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Add("Vary", "Accept, Accept-Encoding")
+
+	h.serve(w, r, g, f, enc, "")
+}
+
+// rendered is the grid response before compression.
+type rendered struct {
+	format      Format
+	contentType string
+	disposition string
+	etag        string
+	body        []byte
+}
+
+// setHeader sets content headers of the response.
+func (rd *rendered) setHeader(hdr http.Header) {
+	hdr.Set("Content-Type", rd.contentType)
+	if rd.disposition != "" {
+		hdr.Set("Content-Disposition", rd.disposition)
+	}
+}
+
+// serve writes the grid in the format f unless it isn't modified
+// according to If-None-Match. JSON and XLSX are rendered in memory,
+// CSV and HTML are written to the response while they are exported.
+// The response is stored in Cache by key if key isn't empty.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, g *Grid, f Format, enc Encoding, key string) {
+
+	hash, err := g.Hash()
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	// the tag is weak, as compressed and identity responses share it.
+	etag := hash[:32] + "-" + string(f)
+	if f == FormatJSON {
+		etag += "-" + string(enc)
+	}
+	etag = `W/"` + etag + `"`
+
+	if NotModified(w, r, etag) {
+		return
+	}
+
+	name := h.FileName
+	if name == "" {
+		name = "grid"
+	}
+
+	rd := rendered{format: f, contentType: f.ContentType(), etag: etag}
+	if f == FormatXLSX || f == FormatCSV {
+		rd.disposition = ContentDisposition("attachment", name+"."+string(f))
+	}
+
+	if f == FormatJSON || f == FormatXLSX {
+		var buf bytes.Buffer
+		if f == FormatJSON {
+			rd.contentType = enc.ContentType()
+			err = g.Encode(&buf, enc)
+		} else {
+			err = g.WriteExcel(&buf, h.ExcelOptions...)
+		}
+		if err != nil {
+			h.error(w, r, err)
+			return
+		}
+
+		rd.body = buf.Bytes()
+		if key != "" {
+			h.Cache.set(key, &rd, h.Tags)
+		}
+		h.write(w, r, &rd)
+		return
+	}
+
+	bw := newBodyWriter(w, r, &rd)
+	var dst io.Writer = bw
+	var cached bytes.Buffer
+	if key != "" {
+		dst = io.MultiWriter(bw, &cached)
+	}
+
+	err = h.export(dst, g, f, name)
+	if err == nil {
+		err = bw.Close()
+	}
+	if err != nil {
+		if bw.sent() {
+			// the status is sent already, the client gets
+			// the broken response.
+			panic(http.ErrAbortHandler)
+		}
+		h.error(w, r, err)
+		return
+	}
+
+	if key != "" {
+		rd.body = cached.Bytes()
+		h.Cache.set(key, &rd, h.Tags)
+	}
+}
+
+// export writes the grid to w as CSV or HTML page titled name.
+func (h *Handler) export(w io.Writer, g *Grid, f Format, name string) error {
+	if f == FormatCSV {
+		return g.WriteCSV(w, h.ExcelOptions...)
+	}
+
+	if _, err := io.WriteString(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>"+
+		html.EscapeString(name)+"</title>\n</head>\n<body>\n"); err != nil {
+		return err
+	}
+	if err := g.WriteHTML(w, h.ExcelOptions...); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</body>\n</html>\n")
+	return err
+}
+
+// write writes the rendered grid compressed by gzip if the client
+// accepts it. The response is aborted if writing fails.
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, rd *rendered) {
+
+	if NotModified(w, r, rd.etag) {
+		return
+	}
+
+	bw := newBodyWriter(w, r, rd)
+	if !bw.gzip || len(rd.body) < gzipMinSize {
+		rd.setHeader(w.Header())
+		w.Header().Set("Content-Length", strconv.Itoa(len(rd.body)))
+		if _, err := w.Write(rd.body); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
+	if _, err := bw.Write(rd.body); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if err := bw.Close(); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// bodyWriter writes the response body. The body is buffered until
+// gzipMinSize bytes are written, then it's compressed by gzip if the
+// client accepts it. Headers are set when the body is sent, so the error
+// response can be written instead of the body not sent yet.
+type bodyWriter struct {
+	w    http.ResponseWriter
+	rd   *rendered
+	gzip bool
+	buf  []byte
+	dst  io.Writer // nil until the body is sent.
+	zw   *gzip.Writer
+}
+
+func newBodyWriter(w http.ResponseWriter, r *http.Request, rd *rendered) *bodyWriter {
+	// XLSX is the zip archive already.
+	return &bodyWriter{w: w, rd: rd, gzip: rd.format != FormatXLSX && acceptsGzip(r)}
+}
+
+func (bw *bodyWriter) Write(p []byte) (int, error) {
+	if bw.dst != nil {
+		return bw.dst.Write(p)
+	}
+
+	bw.buf = append(bw.buf, p...)
+	if len(bw.buf) < gzipMinSize {
+		return len(p), nil
+	}
+
+	bw.rd.setHeader(bw.w.Header())
+	bw.dst = bw.w
+	if bw.gzip {
+		bw.w.Header().Set("Content-Encoding", "gzip")
+		bw.zw = gzip.NewWriter(bw.w)
+		bw.dst = bw.zw
+	}
+
+	buf := bw.buf
+	bw.buf = nil
+	if _, err := bw.dst.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sent reports whether the response is started.
+func (bw *bodyWriter) sent() bool {
+	return bw.dst != nil
+}
+
+// Close writes the body shorter than gzipMinSize or completes
+// the compressed body.
+func (bw *bodyWriter) Close() error {
+	if bw.zw != nil {
+		return bw.zw.Close()
+	}
+	if bw.dst != nil {
+		return nil
+	}
+
+	bw.rd.setHeader(bw.w.Header())
+	bw.w.Header().Set("Content-Length", strconv.Itoa(len(bw.buf)))
+	bw.dst = bw.w
+	_, err := bw.w.Write(bw.buf)
+	return err
+}
+
+// cacheKey returns the key of the response in Cache.
+func (h *Handler) cacheKey(r *http.Request, f Format, enc Encoding) string {
+	key := r.URL.Path + "?" + r.URL.Query().Encode()
+	if h.CacheKey != nil {
+		key = h.CacheKey(r)
+	}
+	return key + "|" + string(f) + "|" + string(enc)
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, err error) {
+	if h.Error != nil {
+		h.Error(w, r, err)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// negotiate returns the format and JSON encoding of the response.
+// The status is 400 for unknown parameter values and 406 if no format
+// is acceptable.
+func (h *Handler) negotiate(r *http.Request) (Format, Encoding, int) {
+
+	param := h.FormatParam
+	if param == "" {
+		param = "format"
+	}
+
+	q := r.URL.Query()
+	enc, err := ParseEncoding(q.Get("enc"))
+	if err != nil {
+		return "", "", http.StatusBadRequest
+	}
+
+	if s := q.Get(param); s != "" {
+		switch f := Format(s); f {
+		case FormatJSON, FormatXLSX, FormatCSV, FormatHTML:
+			return f, enc, http.StatusOK
+		}
+		return "", "", http.StatusBadRequest
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON, enc, http.StatusOK
+	}
+
+	best, bestQ := -1, 0.0
+	for i := range negotiated {
+		if aq := acceptQuality(accept, negotiated[i].media); aq > bestQ {
+			best, bestQ = i, aq
+		}
+	}
+	if best == -1 {
+		return "", "", http.StatusNotAcceptable
+	}
+
+	n := negotiated[best]
+	if n.format == FormatJSON && q.Get("enc") == "" {
+		enc = n.encoding
+	}
+	return n.format, enc, http.StatusOK
+}
+
+// acceptQuality returns the quality of the media type in Accept header
+// given by the most specific media range, -1 if the type isn't accepted.
+func acceptQuality(accept, media string) float64 {
+	typ := media[:strings.IndexByte(media, '/')]
+
+	q, spec := -1.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		s := -1
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case media:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > spec {
+			spec, q = s, quality(params[1:])
+		}
+	}
+	return q
+}
+
+// acceptsGzip reports whether Accept-Encoding header of r allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case "gzip", "*":
+			return quality(params[1:]) > 0
+		}
+	}
+	return false
+}
+
+// quality returns the value of q parameter, 1 if it's absent.
+func quality(params []string) float64 {
+	for _, p := range params {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "q=") {
+			q, err := strconv.ParseFloat(p[2:], 64)
+			if err != nil {
+				return 0
+			}
+			return q
+		}
+	}
+	return 1
+}
+
+// ContentDisposition returns Content-Disposition header value of the type
+// ("attachment" or "inline") and the file name. Non ASCII names are
+// encoded according to RFC 5987 (filename*), filename holds the name
+// with such characters replaced by "_" for old clients.
+func ContentDisposition(typ, fname string) string {
+
+	var ascii strings.Builder
+	for _, r := range fname {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' {
+			r = '_'
+		}
+		ascii.WriteRune(r)
+	}
+
+	const hexDigits = "0123456789ABCDEF"
+	var ext strings.Builder
+	for i := 0; i < len(fname); i++ {
+		b := fname[i]
+		if isAttrChar(b) {
+			ext.WriteByte(b)
+			continue
+		}
+		ext.WriteByte('%')
+		ext.WriteByte(hexDigits[b>>4])
+		ext.WriteByte(hexDigits[b&0x0f])
+	}
+
+	return typ + `; filename="` + ascii.String() + `"; filename*=UTF-8''` + ext.String()
+}
+
+// isAttrChar reports whether b is attr-char of RFC 5987.
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) != -1
+}
@@ -0,0 +1,60 @@
+package grider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Hash returns hex encoded SHA-256 of JSON representation of the grid,
+// that is the hash of JSON output. It's the same for grids holding
+// the same values: encoding/json writes map keys, as instance codes
+// of ActionSet, in sorted order.
+func (g *Grid) Hash() (string, error) {
+	return contentHash(g)
+}
+
+// Hash returns hex encoded SHA-256 of JSON representation of the page.
+// See Grid.Hash.
+func (p *Page) Hash() (string, error) {
+	return contentHash(p)
+}
+
+func contentHash(v interface{}) (string, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NotModified sets ETag header and writes status 304 Not Modified
+// if If-None-Match header of r matches etag. etag is the quoted value,
+// optionally with weak prefix W/. Tags are compared by weak comparison.
+// It reports whether the response is written:
+//
+//	h, err := page.Hash()
+//	...
+//	if grider.NotModified(w, r, `"`+h+`"`) {
+//		return
+//	}
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	inm := r.Header.Get("If-None-Match")
+	if inm == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	for _, t := range strings.Split(inm, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
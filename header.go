@@ -0,0 +1,144 @@
+package grider
+
+import (
+	"sort"
+	"strings"
+)
+
+// HeaderCell is the cell of the group header row spanning Span columns
+// starting from the column Start. Start and Span count non hidden
+// columns only. Cells with empty Title cover columns having no group
+// on this level.
+type HeaderCell struct {
+	Title string `json:"title"`
+	Start int    `json:"start"`
+	Span  int    `json:"span"`
+}
+
+// BuildHeaderGroups sets HeaderGroups from Group of the columns. Group
+// is a path of titles separated by "/", as instance "Finance/Q1".
+// The first level is the top row of the header. Adjacent columns with
+// the same path prefix share a cell, hidden columns are skipped.
+// HeaderGroups is nil if no column has a group.
+//
+// BuildHeaderGroups is called by the grid builders, call it again after
+// changing Columns directly.
+func (g *Grid) BuildHeaderGroups() {
+	var visible []Column
+	for i := range g.Columns {
+		if !g.Columns[i].Hidden {
+			visible = append(visible, g.Columns[i])
+		}
+	}
+	g.HeaderGroups = headerGroups(visible)
+}
+
+// headerGroups returns header group rows of the columns cols.
+func headerGroups(cols []Column) [][]HeaderCell {
+
+	paths := make([][]string, len(cols))
+	depth := 0
+	for i := range cols {
+		if cols[i].Group == "" {
+			continue
+		}
+		paths[i] = strings.Split(cols[i].Group, "/")
+		if len(paths[i]) > depth {
+			depth = len(paths[i])
+		}
+	}
+
+	if depth == 0 {
+		return nil
+	}
+
+	res := make([][]HeaderCell, depth)
+	for l := 0; l < depth; l++ {
+		var key string
+		for i := range cols {
+			k := ""
+			if l < len(paths[i]) {
+				k = strings.Join(paths[i][:l+1], "/")
+			}
+
+			if i > 0 && k == key {
+				res[l][len(res[l])-1].Span++
+				continue
+			}
+
+			key = k
+			title := ""
+			if l < len(paths[i]) {
+				title = strings.TrimSpace(paths[i][l])
+			}
+			res[l] = append(res[l], HeaderCell{Title: title, Start: i, Span: 1})
+		}
+	}
+	return res
+}
+
+// orderColumns moves columns with the tag attribute order to the front
+// sorted by order. Other columns follow in the struct field order.
+// It returns the permutation of the column indexes for permute
+// or nil if the order is unchanged.
+func (g *Grid) orderColumns() []int {
+	defer g.BuildHeaderGroups()
+
+	perm := make([]int, len(g.Columns))
+	ordered := false
+	for i := range g.Columns {
+		perm[i] = i
+		if g.Columns[i].Order != 0 {
+			ordered = true
+		}
+	}
+
+	if !ordered {
+		return nil
+	}
+
+	sort.SliceStable(perm, func(i, j int) bool {
+		a, b := g.Columns[perm[i]].Order, g.Columns[perm[j]].Order
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+
+	cols := make([]Column, len(perm))
+	for i, j := range perm {
+		cols[i] = g.Columns[j]
+	}
+	g.Columns = cols
+	return perm
+}
+
+// permuteValues returns source values of the row ordered by perm.
+func permuteValues(row []interface{}, perm []int) []interface{} {
+	if perm == nil {
+		return row
+	}
+
+	res := make([]interface{}, len(perm))
+	for i, j := range perm {
+		if j < len(row) {
+			res[i] = row[j]
+		}
+	}
+	return res
+}
+
+// permute returns the row with cells ordered by perm.
+func permute(row []string, perm []int) []string {
+	if perm == nil {
+		return row
+	}
+
+	res := make([]string, len(perm))
+	for i, j := range perm {
+		if j < len(row) {
+			res[i] = row[j]
+		}
+	}
+	return res
+}
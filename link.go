@@ -0,0 +1,182 @@
+package grider
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Link describes a cell of the column with type "link".
+type Link struct {
+	Text   string `json:"text"`
+	URL    string `json:"url"`
+	Target string `json:"target,omitempty"`
+}
+
+// HTML returns the link as HTML anchor. Text and attributes are escaped.
+func (l Link) HTML() string {
+	s := `<a href="` + html.EscapeString(l.URL) + `"`
+	if l.Target != "" {
+		s += ` target="` + html.EscapeString(l.Target) + `"`
+	}
+	return s + ">" + html.EscapeString(l.Text) + "</a>"
+}
+
+// LinkTemplate is a parsed Column.Href like "/customers/{CustomerID}?tab={Tab}".
+// Placeholders refer to the grid columns by name. Values substituted
+// into the path are path escaped, values substituted into the query
+// are query escaped.
+type LinkTemplate struct {
+	parts    []linkPart
+	absolute bool
+}
+
+// linkPart is a literal text or a placeholder if column is not -1.
+type linkPart struct {
+	text   string
+	column int
+	escape func(string) string
+}
+
+// ParseLinkTemplate parses href and resolves placeholders to positions
+// of the columns in cols.
+func ParseLinkTemplate(href string, cols []Column) (*LinkTemplate, error) {
+
+	lt := LinkTemplate{absolute: strings.HasPrefix(href, "http")}
+
+	escape := url.PathEscape
+	start := 0
+	for i := 0; i < len(href); i++ {
+		switch href[i] {
+		case '?':
+			escape = url.QueryEscape
+		case '#':
+			escape = url.PathEscape
+		case '{':
+			end := strings.IndexByte(href[i:], '}')
+			if end < 0 {
+				return nil, errors.New("unclosed placeholder in href " + href)
+			}
+			end += i
+
+			name := href[i+1 : end]
+			p := linkPart{text: href[i : end+1], column: -1, escape: escape}
+			for k := range cols {
+				if cols[k].Name == name {
+					p.column = k
+					break
+				}
+			}
+			if p.column == -1 {
+				return nil, errors.New("invalid placeholder " + p.text + " in href " + href)
+			}
+
+			if start < i {
+				lt.parts = append(lt.parts, linkPart{text: href[start:i], column: -1})
+			}
+			lt.parts = append(lt.parts, p)
+			start = end + 1
+			i = end
+		}
+	}
+
+	if start < len(href) {
+		lt.parts = append(lt.parts, linkPart{text: href[start:], column: -1})
+	}
+
+	return &lt, nil
+}
+
+// Expand returns URL built from the row values. The prefix is added
+// to the template if it does not start with "http".
+func (lt *LinkTemplate) Expand(prefix string, row []string) string {
+	var b strings.Builder
+	if !lt.absolute {
+		b.WriteString(prefix)
+	}
+	for _, p := range lt.parts {
+		if p.column == -1 {
+			b.WriteString(p.text)
+			continue
+		}
+		if p.column < len(row) {
+			b.WriteString(p.escape(row[p.column]))
+		}
+	}
+	return b.String()
+}
+
+// linkTemplates parses Href of non hidden link columns.
+func linkTemplates(cols []Column) (map[int]*LinkTemplate, error) {
+	res := make(map[int]*LinkTemplate)
+	for i := range cols {
+		if cols[i].Type != "link" || cols[i].Hidden || cols[i].Href == "" {
+			continue
+		}
+		lt, err := ParseLinkTemplate(cols[i].Href, cols)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = lt
+	}
+	return res, nil
+}
+
+// links builds links of the row. Elements of the result are nil
+// for the columns what are not links.
+func (g *Grid) links(lts map[int]*LinkTemplate, row []string) []*Link {
+	prefix := g.config().LinkPrefix
+	res := make([]*Link, len(row))
+	for col, lt := range lts {
+		if col >= len(row) {
+			continue
+		}
+		res[col] = &Link{
+			Text:   row[col],
+			URL:    lt.Expand(prefix, row),
+			Target: g.Columns[col].Target,
+		}
+	}
+	return res
+}
+
+// ApplyLinks fills RowLinks with structured links built from Href
+// of the link columns. Rows are not modified.
+func (g *Grid) ApplyLinks() error {
+	lts, err := linkTemplates(g.Columns)
+	if err != nil {
+		return err
+	}
+
+	if len(lts) == 0 {
+		g.RowLinks = nil
+		return nil
+	}
+
+	g.RowLinks = make([][]*Link, len(g.Rows))
+	for row := range g.Rows {
+		g.RowLinks[row] = g.links(lts, g.Rows[row])
+	}
+	return nil
+}
+
+// ReplaceCellWithFullLinks replaces text of the link cells with escaped
+// HTML anchors built from Href of the link columns.
+func (g *Grid) ReplaceCellWithFullLinks() error {
+	lts, err := linkTemplates(g.Columns)
+	if err != nil {
+		return err
+	}
+
+	for row := range g.Rows {
+		// links are built before any replacement, so placeholders
+		// always refer to the original values.
+		for col, l := range g.links(lts, g.Rows[row]) {
+			if l != nil {
+				g.Rows[row][col] = l.HTML()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package grider
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// ApplySliceOfMaps converts records without static struct to Grid.
+// Columns are created from the union of the record keys. Columns listed
+// in defs go first in the given order and take attributes from defs,
+// other keys follow sorted by name. Columns having Order, set by defs
+// or overrides, are moved to the front as by the tag attribute order.
+// Values are formatted by their
+// runtime type the same way as struct attributes in ApplySliceOfStruct.
+// Columns of numeric keys not listed in defs have type "number" and are
+// right aligned.
+func (g *Grid) ApplySliceOfMaps(src []map[string]interface{}, defs ...Column) *Grid {
+
+	keys := make(map[string]struct{})
+	for i := range src {
+		for k := range src[i] {
+			keys[k] = struct{}{}
+		}
+	}
+
+	order := make([]string, 0, len(keys))
+	for k := range keys {
+		order = append(order, k)
+	}
+	sort.Strings(order)
+
+	g.applyMaps(src, defs, order)
+	return g
+}
+
+// ApplyJSONRecords reads JSON array of objects from r and converts it
+// to Grid like ApplySliceOfMaps does. Keys not listed in defs follow
+// in order of their first appearance in the input.
+func (g *Grid) ApplyJSONRecords(r io.Reader, defs ...Column) error {
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	var (
+		src   []map[string]interface{}
+		order []string
+		seen  = make(map[string]struct{})
+	)
+
+	for dec.More() {
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+
+		rec := make(map[string]interface{})
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			k, ok := t.(string)
+			if !ok {
+				return errors.New("json record key expected")
+			}
+
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+			rec[k] = v
+
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				order = append(order, k)
+			}
+		}
+
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+		src = append(src, rec)
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		return err
+	}
+
+	g.applyMaps(src, defs, order)
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != d {
+		return errors.New("json: expected " + d.String())
+	}
+	return nil
+}
+
+// applyMaps creates columns from defs and keys and converts records
+// to rows.
+func (g *Grid) applyMaps(src []map[string]interface{}, defs []Column, keys []string) {
+
+	g.Columns = make([]Column, 0, len(defs)+len(keys))
+	used := make(map[string]bool, len(defs)+len(keys))
+	for _, c := range defs {
+		if used[c.Name] {
+			continue
+		}
+		if c.Title == "" {
+			c.Title = g.columnTitle(c.Name)
+		}
+		used[c.Name] = true
+		g.Columns = append(g.Columns, c)
+	}
+
+	for _, k := range keys {
+		if used[k] {
+			continue
+		}
+		used[k] = true
+
+		c := Column{Name: k, Title: g.columnTitle(k)}
+		if isNumber(firstValue(src, k)) {
+			c.Type = "number"
+			c.Align = "right"
+		}
+		g.Columns = append(g.Columns, c)
+	}
+	g.applyOverrides()
+	g.orderColumns()
+
+	cfg := g.config()
+	for i := range src {
+		row := make([]string, len(g.Columns))
+		values := make([]interface{}, len(g.Columns))
+		for j := range g.Columns {
+			values[j] = src[i][g.Columns[j].Name]
+			row[j] = cfg.formatValue(values[j])
+		}
+		g.Rows = append(g.Rows, row)
+		if g.option.sourceValues {
+			g.values = append(g.values, values)
+		}
+	}
+}
+
+// firstValue returns the first non nil value of the key k.
+func firstValue(src []map[string]interface{}, k string) interface{} {
+	for i := range src {
+		if v := src[i][k]; v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func isNumber(v interface{}) bool {
+	if _, ok := v.(json.Number); ok {
+		return true
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// formatValue converts value of any runtime type to string. Nested
+// maps and slices are converted to JSON.
+func (c *Config) formatValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			buf, err := json.Marshal(v)
+			if err != nil {
+				return "?????"
+			}
+			return string(buf)
+		}
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return ""
+		}
+	}
+	return c.formatAttribute(rv, "")
+}
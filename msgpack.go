@@ -0,0 +1,352 @@
+package grider
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Minimal MessagePack (https://msgpack.org/) encoder and decoder of
+// the values produced by encoding/json: nil, bool, json.Number, float64,
+// string, []interface{} and map[string]interface{}. Maps are written
+// with sorted keys.
+
+type msgpackWriter struct {
+	w   *bufio.Writer
+	buf [9]byte
+}
+
+func newMsgpackWriter(w io.Writer) *msgpackWriter {
+	return &msgpackWriter{w: bufio.NewWriter(w)}
+}
+
+func (m *msgpackWriter) flush() error {
+	return m.w.Flush()
+}
+
+// head writes the type byte b followed by n as a big endian integer
+// of size bytes.
+func (m *msgpackWriter) head(b byte, size int, n uint64) {
+	m.buf[0] = b
+	switch size {
+	case 1:
+		m.buf[1] = byte(n)
+	case 2:
+		binary.BigEndian.PutUint16(m.buf[1:], uint16(n))
+	case 4:
+		binary.BigEndian.PutUint32(m.buf[1:], uint32(n))
+	case 8:
+		binary.BigEndian.PutUint64(m.buf[1:], n)
+	}
+	m.w.Write(m.buf[:1+size])
+}
+
+func (m *msgpackWriter) writeNil() {
+	m.w.WriteByte(0xc0)
+}
+
+func (m *msgpackWriter) writeBool(b bool) {
+	if b {
+		m.w.WriteByte(0xc3)
+		return
+	}
+	m.w.WriteByte(0xc2)
+}
+
+func (m *msgpackWriter) writeInt(n int64) {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		m.w.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		m.w.WriteByte(byte(n))
+	case n >= 0 && n <= math.MaxUint32:
+		m.head(0xce, 4, uint64(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		m.head(0xd2, 4, uint64(uint32(int32(n))))
+	default:
+		m.head(0xd3, 8, uint64(n))
+	}
+}
+
+func (m *msgpackWriter) writeFloat(f float64) {
+	m.head(0xcb, 8, math.Float64bits(f))
+}
+
+func (m *msgpackWriter) writeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		m.w.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		m.head(0xd9, 1, uint64(n))
+	case n <= math.MaxUint16:
+		m.head(0xda, 2, uint64(n))
+	default:
+		m.head(0xdb, 4, uint64(n))
+	}
+	m.w.WriteString(s)
+}
+
+func (m *msgpackWriter) writeArrayHeader(n int) {
+	switch {
+	case n < 16:
+		m.w.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		m.head(0xdc, 2, uint64(n))
+	default:
+		m.head(0xdd, 4, uint64(n))
+	}
+}
+
+func (m *msgpackWriter) writeMapHeader(n int) {
+	switch {
+	case n < 16:
+		m.w.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		m.head(0xde, 2, uint64(n))
+	default:
+		m.head(0xdf, 4, uint64(n))
+	}
+}
+
+func (m *msgpackWriter) writeStrings(a []string) {
+	m.writeArrayHeader(len(a))
+	for i := range a {
+		m.writeString(a[i])
+	}
+}
+
+func (m *msgpackWriter) writeValue(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		m.writeNil()
+	case bool:
+		m.writeBool(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			m.writeInt(n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		m.writeFloat(f)
+	case int:
+		m.writeInt(int64(v))
+	case int64:
+		m.writeInt(v)
+	case float64:
+		m.writeFloat(v)
+	case string:
+		m.writeString(v)
+	case []string:
+		m.writeStrings(v)
+	case []interface{}:
+		m.writeArrayHeader(len(v))
+		for i := range v {
+			if err := m.writeValue(v[i]); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		m.writeMapHeader(len(keys))
+		for _, k := range keys {
+			m.writeString(k)
+			if err := m.writeValue(v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("msgpack: unsupported type")
+	}
+	return nil
+}
+
+// msgpackSizes holds size of the length or the value following
+// the type byte.
+var msgpackSizes = map[byte]int{
+	0xc4: 1, 0xc5: 2, 0xc6: 4, // bin
+	0xd9: 1, 0xda: 2, 0xdb: 4, // str
+	0xdc: 2, 0xdd: 4, // array
+	0xde: 2, 0xdf: 4, // map
+	0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8, // uint
+	0xd0: 1, 0xd1: 2, 0xd2: 4, 0xd3: 8, // int
+	0xca: 4, 0xcb: 8, // float
+}
+
+// msgpackMaxDepth is the maximal nesting of arrays and maps read
+// by msgpackReader.
+const msgpackMaxDepth = 64
+
+type msgpackReader struct {
+	r     *bufio.Reader
+	buf   [8]byte
+	depth int // nesting of the array or map being read
+}
+
+func newMsgpackReader(r io.Reader) *msgpackReader {
+	return &msgpackReader{r: bufio.NewReader(r)}
+}
+
+// uint reads big endian unsigned integer of size bytes.
+func (m *msgpackReader) uint(size int) (uint64, error) {
+	if _, err := io.ReadFull(m.r, m.buf[:size]); err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(m.buf[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(m.buf[:])), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(m.buf[:])), nil
+	}
+	return binary.BigEndian.Uint64(m.buf[:]), nil
+}
+
+// str reads the string of n bytes. The buffer grows while data is read,
+// so the corrupted length doesn't allocate memory in advance.
+func (m *msgpackReader) str(n uint64) (string, error) {
+	var sb strings.Builder
+	k, err := io.CopyN(&sb, m.r, int64(n))
+	if err != nil && uint64(k) != n {
+		return "", io.ErrUnexpectedEOF
+	}
+	return sb.String(), nil
+}
+
+// enter starts reading of the nested array or map. It returns an error
+// if nesting exceeds msgpackMaxDepth, so untrusted input can't exhaust
+// the stack.
+func (m *msgpackReader) enter() error {
+	if m.depth == msgpackMaxDepth {
+		return errors.New("msgpack: nesting exceeds " + strconv.Itoa(msgpackMaxDepth) + " levels")
+	}
+	m.depth++
+	return nil
+}
+
+func (m *msgpackReader) array(n uint64) ([]interface{}, error) {
+	if err := m.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { m.depth-- }()
+
+	res := make([]interface{}, 0, minUint(n, 1024))
+	for i := uint64(0); i < n; i++ {
+		v, err := m.readValue()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func minUint(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (m *msgpackReader) object(n uint64) (map[string]interface{}, error) {
+	if err := m.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { m.depth-- }()
+
+	res := make(map[string]interface{}, minUint(n, 1024))
+	for i := uint64(0); i < n; i++ {
+		k, err := m.readValue()
+		if err != nil {
+			return nil, err
+		}
+		ks, ok := k.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map key expected to be a string")
+		}
+		if res[ks], err = m.readValue(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// readValue reads the next value. Integers are returned as int64,
+// floats as float64, binary data as string.
+func (m *msgpackReader) readValue() (interface{}, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return m.object(uint64(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return m.array(uint64(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return m.str(uint64(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	}
+
+	size, ok := msgpackSizes[b]
+	if !ok {
+		return nil, errors.New("msgpack: unsupported type 0x" + strconv.FormatUint(uint64(b), 16))
+	}
+	n, err := m.uint(size)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b {
+	case 0xc4, 0xc5, 0xc6, 0xd9, 0xda, 0xdb:
+		return m.str(n)
+	case 0xdc, 0xdd:
+		return m.array(n)
+	case 0xde, 0xdf:
+		return m.object(n)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		if n > math.MaxInt64 {
+			return float64(n), nil
+		}
+		return int64(n), nil
+	case 0xd0:
+		return int64(int8(n)), nil
+	case 0xd1:
+		return int64(int16(n)), nil
+	case 0xd2:
+		return int64(int32(n)), nil
+	case 0xd3:
+		return int64(n), nil
+	case 0xca:
+		return float64(math.Float32frombits(uint32(n))), nil
+	}
+	return math.Float64frombits(n), nil
+}
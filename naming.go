@@ -0,0 +1,130 @@
+package grider
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy defines how column names are built from names
+// of the struct attributes.
+type NamingStrategy int
+
+const (
+	// NamingGo joins Go names of the parent and the attribute: AddressCity.
+	// Only the nearest parent is used. Embedded structs add their type name.
+	NamingGo NamingStrategy = iota
+
+	// NamingSnake joins snake case names of all parents: address_city.
+	NamingSnake
+
+	// NamingDotPath joins lower camel case names of all parents
+	// with a dot: address.postCode.
+	NamingDotPath
+
+	// NamingJSON joins json tag names of all parents with a dot.
+	// Go name is used if json tag has no name.
+	NamingJSON
+)
+
+// WithNaming sets the strategy of building column names. The struct
+// field tag attribute name overrides the name of the single attribute.
+func WithNaming(ns NamingStrategy) func(*Option) {
+	return func(s *Option) {
+		s.naming = ns
+	}
+}
+
+// attributeName returns the name of the attribute used as a part
+// of the column name.
+func (g *Grid) attributeName(tf reflect.StructField, tag string) string {
+	if s := extractTagAttr(tag, "name"); s != "" {
+		return s
+	}
+
+	switch g.option.naming {
+	case NamingSnake:
+		return ToSnakeCase(tf.Name)
+	case NamingDotPath:
+		return lowerFirst(tf.Name)
+	case NamingJSON:
+		name := tf.Tag.Get("json")
+		if i := strings.Index(name, ","); i >= 0 {
+			name = name[:i]
+		}
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return tf.Name
+}
+
+// joinAttributeNames joins the parent name and the attribute name.
+func (g *Grid) joinAttributeNames(parentAttribute, attribute string) string {
+	if parentAttribute == "" {
+		return attribute
+	}
+
+	switch g.option.naming {
+	case NamingSnake:
+		return parentAttribute + "_" + attribute
+	case NamingDotPath, NamingJSON:
+		return parentAttribute + "." + attribute
+	}
+	return parentAttribute + attribute
+}
+
+// nestedParent returns the parent name passed to the attributes
+// of the nested struct attribute.
+func (g *Grid) nestedParent(parentAttribute, attribute string, tf reflect.StructField) string {
+	if g.option.naming == NamingGo {
+		return attribute
+	}
+
+	// fields of embedded structs are promoted.
+	if tf.Anonymous {
+		return parentAttribute
+	}
+	return g.joinAttributeNames(parentAttribute, attribute)
+}
+
+// checkColumnNames panics if several attributes produce the same
+// column name. Duplicates of NamingGo are allowed as before naming
+// strategies were added, Validate reports them.
+func (g *Grid) checkColumnNames(cols []Column) {
+	if g.option.naming == NamingGo {
+		return
+	}
+
+	seen := make(map[string]struct{}, len(cols))
+	var dup []string
+	for i := range cols {
+		if _, ok := seen[cols[i].Name]; ok {
+			dup = append(dup, cols[i].Name)
+			continue
+		}
+		seen[cols[i].Name] = struct{}{}
+	}
+
+	if len(dup) > 0 {
+		panic("column names produced by more than one attribute: " + strings.Join(dup, ", ") +
+			"; use struct field tag attribute name= or another naming strategy")
+	}
+}
+
+// lowerFirst converts the leading upper case letters to lower case
+// keeping the beginning of the next word: ID -> id, URLPath -> urlPath.
+func lowerFirst(s string) string {
+	r := []rune(s)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
@@ -0,0 +1,272 @@
+package grider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ColumnOverride holds column attributes replacing attributes set by
+// the struct field tags. Nil attributes are not changed.
+type ColumnOverride struct {
+	Type       *string `json:"type,omitempty"`
+	Title      *string `json:"title,omitempty"`
+	Align      *string `json:"align,omitempty"`
+	Hidden     *bool   `json:"hidden,omitempty"`
+	Sortable   *bool   `json:"sortable,omitempty"`
+	Filterable *bool   `json:"filterable,omitempty"`
+	Href       *string `json:"href,omitempty"`
+	Perm       *string `json:"perm,omitempty"`
+	Caption    *string `json:"caption,omitempty"`
+	Method     *string `json:"method,omitempty"`
+	Icons      *string `json:"icons,omitempty"`
+	IconsAlign *string `json:"ialign,omitempty"`
+	Target     *string `json:"target,omitempty"`
+	Order      *int    `json:"order,omitempty"`
+	Width      *int    `json:"width,omitempty"`
+	MinWidth   *int    `json:"minWidth,omitempty"`
+	Pin        *string `json:"pin,omitempty"`
+	Group      *string `json:"group,omitempty"`
+	NoSearch   *bool   `json:"noSearch,omitempty"`
+	Sort       *string `json:"sort,omitempty"`
+
+	Filter *FilterSpec `json:"filter,omitempty"`
+}
+
+// Apply sets the not nil attributes of the override to the column c.
+// Filterable false removes the filter of the column.
+func (o *ColumnOverride) Apply(c *Column) {
+	setString(&c.Type, o.Type)
+	setString(&c.Title, o.Title)
+	setString(&c.Align, o.Align)
+	setBool(&c.Hidden, o.Hidden)
+	setBool(&c.Sortable, o.Sortable)
+	setBool(&c.Filterable, o.Filterable)
+	setString(&c.Href, o.Href)
+	setString(&c.Perm, o.Perm)
+	setString(&c.Caption, o.Caption)
+	setString(&c.Method, o.Method)
+	setString(&c.Icons, o.Icons)
+	setString(&c.IconsAlign, o.IconsAlign)
+	setString(&c.Target, o.Target)
+	setInt(&c.Order, o.Order)
+	setInt(&c.Width, o.Width)
+	setInt(&c.MinWidth, o.MinWidth)
+	setString(&c.Pin, o.Pin)
+	setString(&c.Group, o.Group)
+	setBool(&c.NoSearch, o.NoSearch)
+	setString(&c.Sort, o.Sort)
+	if o.Filter != nil {
+		fs := *o.Filter
+		c.Filter = &fs
+		c.completeFilter()
+	}
+
+	// the filter declared by the tags is dropped with filterable.
+	if o.Filterable != nil && !*o.Filterable {
+		c.Filter = nil
+	}
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setBool(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setInt(dst *int, src *int) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// Overrides is the registry of column overrides keyed by the grid name
+// and the column name. The grid name is set by WithName. Overrides are
+// applied to the columns built by ApplySliceOfStruct, ApplySliceOfMaps,
+// ApplyJSONRecords and ApplySQLRows if Config.Overrides is set.
+//
+// Overrides is safe for concurrent use.
+type Overrides struct {
+	mu    sync.RWMutex
+	grids map[string]map[string]ColumnOverride
+
+	// dir and mtimes describe files loaded by LoadDir.
+	dir    string
+	mtimes map[string]time.Time
+}
+
+// NewOverrides returns an empty registry.
+func NewOverrides() *Overrides {
+	return &Overrides{grids: make(map[string]map[string]ColumnOverride)}
+}
+
+// Set adds or replaces the override of the column of the grid.
+func (o *Overrides) Set(grid, column string, co ColumnOverride) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.grids[grid] == nil {
+		o.grids[grid] = make(map[string]ColumnOverride)
+	}
+	o.grids[grid][column] = co
+}
+
+// Lookup returns the override of the column of the grid.
+func (o *Overrides) Lookup(grid, column string) (ColumnOverride, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	co, ok := o.grids[grid][column]
+	return co, ok
+}
+
+// LoadDir replaces the registry content by overrides read from *.json
+// files of the directory dir. The file name without extension is the grid
+// name, the file holds an object of column overrides by the column name:
+//
+//	{"Phone": {"hidden": true}, "Name": {"title": "Customer"}}
+//
+// Unknown attributes are reported as errors. The registry is unchanged
+// if any file can't be loaded.
+func (o *Overrides) LoadDir(dir string) error {
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	grids := make(map[string]map[string]ColumnOverride, len(files))
+	mtimes := make(map[string]time.Time, len(files))
+	for _, fn := range files {
+		fi, err := os.Stat(fn)
+		if err != nil {
+			return err
+		}
+
+		cols, err := readOverrides(fn)
+		if err != nil {
+			return err
+		}
+
+		grids[strings.TrimSuffix(filepath.Base(fn), ".json")] = cols
+		mtimes[fn] = fi.ModTime()
+	}
+
+	o.mu.Lock()
+	o.grids = grids
+	o.dir = dir
+	o.mtimes = mtimes
+	o.mu.Unlock()
+	return nil
+}
+
+func readOverrides(fn string) (map[string]ColumnOverride, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+
+	var cols map[string]ColumnOverride
+	if err := dec.Decode(&cols); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return cols, nil
+}
+
+// changed returns true if files of the directory loaded by LoadDir
+// are added, removed or modified since the last load.
+func (o *Overrides) changed() (bool, error) {
+	o.mu.RLock()
+	dir, mtimes := o.dir, o.mtimes
+	o.mu.RUnlock()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return false, err
+	}
+	if len(files) != len(mtimes) {
+		return true, nil
+	}
+
+	for _, fn := range files {
+		fi, err := os.Stat(fn)
+		if err != nil {
+			return false, err
+		}
+		if mt, ok := mtimes[fn]; !ok || !mt.Equal(fi.ModTime()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Watch checks the directory loaded by LoadDir every interval and
+// reloads it if any file is added, removed or modified. Errors are passed
+// to onError if it's not nil, the previous overrides are kept on error.
+// Watch returns when ctx is done.
+func (o *Overrides) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		ok, err := o.changed()
+		if err == nil && ok {
+			o.mu.RLock()
+			dir := o.dir
+			o.mu.RUnlock()
+			err = o.LoadDir(dir)
+		}
+
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// WithName sets the grid name used to find column overrides.
+func WithName(name string) func(*Option) {
+	return func(s *Option) {
+		s.name = name
+	}
+}
+
+// applyOverrides applies overrides of the config to the grid columns.
+func (g *Grid) applyOverrides() {
+	o := g.config().Overrides
+	if o == nil || g.option.name == "" {
+		return
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	cols := o.grids[g.option.name]
+	for i := range g.Columns {
+		if co, ok := cols[g.Columns[i].Name]; ok {
+			co.Apply(&g.Columns[i])
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package grider
 
+import (
+	"encoding/json"
+	"errors"
+)
+
 // Page:Header
 // 		[*Tab1:Header] [Tab2:Header]
 // 			Tab1:Widget		Tab1:Widget
@@ -24,6 +29,20 @@ type Page struct {
 
 	// Footer описывает содержимое нижней части окна.
 	//Footer *Footer `json:"footer,omitempty"`
+
+	// cfg is the config set by SetConfig.
+	cfg *Config
+}
+
+// JSON returns JSON representation of the page. The page is validated
+// first if debug mode of the page's config is on.
+func (p *Page) JSON() ([]byte, error) {
+	if p.config().Debug {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(p)
 }
 
 // Tab описывает содержимое одного связанного объекта.
@@ -262,6 +281,9 @@ const (
 	LineTypeSuggestion LineType = "suggestion"
 )
 
+// lineTypes lists all values of LineType.
+var lineTypes = []LineType{LineTypeDefault, LineTypeHref, LineTypeExtHref, LineTypeRefbook, LineTypeSuggestion}
+
 // RefBookType описывает параметры строчки которая является изменяемым элементом справочника.
 type RefBookType struct {
 	// Name содержит название справочника из /dictionary
@@ -291,88 +313,48 @@ type SuggestionType struct {
 // type Footer struct {
 // 	Media []Media `json:"media,omitempty"`
 // }
+
+// AssignActionSet collects action codes referenced by the page, its tabs
+// and widgets and assigns them values from supported.
 func (p *Page) AssignActionSet(supported ActionSet) error {
 	p.Action = NewActionSet()
-	p.Action.Add(p.PageActions)
-	for i := range p.Tabs {
-		p.Action.Add(p.Tabs[i].TabActions)
-		p.assignActionCode(p.Tabs[i].Widgets)
-	}
 
-	p.assignActionCode(p.Widgets)
+	err := p.Walk(Visitor{
+		ActionCode: collectActionCode(p.Action),
+	})
+	if err != nil {
+		return err
+	}
 
 	return p.Action.AssignActionValues(supported)
 }
 
-func (p *Page) assignActionCode(ws []Widgeter) {
-	for i := range ws {
-		switch ws[i].WidgetType() {
-		case AttrValueType:
-			w := ws[i].(AttrValueWidget)
-			p.Action.Add(w.Actions)
-			for j := range w.Lines {
-				p.Action.Add(w.Lines[j].Actions)
-			}
-		case MediaType:
-			w := ws[i].(MediaWidget)
-			p.Action.Add(w.Actions)
-		case MapType:
-			break
-		case ChartType:
-			break
-		case ContentType:
-			w := ws[i].(ContentWidget)
-			p.Action.Add(w.Actions)
-			break
-		case CustomType:
-			break
-		case GridType:
-			g := ws[i].(GridWidget)
-			p.Action.Add(g.Grid.GridActions)
-			for j := range g.Grid.RowActions {
-				p.Action.Add(g.Grid.RowActions[j])
-			}
-		}
+// AssignActionSet collects action codes referenced by the widget lw
+// and assigns them values from as. The result is stored in the Action
+// attribute of embedded *Widget.
+//
+// A widget passed by value must have non nil *Widget, otherwise
+// the assigned action set would be lost on a copy.
+func AssignActionSet(lw Widgeter, as ActionSet) error {
+	w := ensureBaseWidget(lw)
+	if w == nil {
+		return errors.New("widget " + lw.WidgetType().String() + " has no *Widget to hold action set")
 	}
+
+	w.Action = NewActionSet()
+	err := WalkWidget(lw, Visitor{
+		ActionCode: collectActionCode(w.Action),
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Action.AssignActionValues(as)
 }
 
-func AssignActionSet(lw Widgeter, as ActionSet) error {
-	var err error
-	switch lw.WidgetType() {
-	case AttrValueType:
-		w := lw.(AttrValueWidget)
-		w.Action = NewActionSet()
-		w.Action.Add(w.Actions)
-		for j := range w.Lines {
-			w.Action.Add(w.Lines[j].Actions)
-		}
-		err = w.Action.AssignActionValues(as)
-	case MediaType:
-		w := lw.(MediaWidget)
-		w.Action = NewActionSet()
-		w.Action.Add(w.Actions)
-		err = w.Action.AssignActionValues(as)
-	case MapType:
-		break
-	case ChartType:
-		break
-	case CustomType:
-		break
-	case ContentType:
-		w := lw.(ContentWidget)
-		w.Action = NewActionSet()
-		w.Action.Add(w.Actions)
-		err = w.Action.AssignActionValues(as)
-		break
-	case GridType:
-		w := lw.(GridWidget)
-		w.Action = NewActionSet()
-		w.Action.Add(w.Actions)
-		w.Action.Add(w.Grid.GridActions)
-		for i := range w.Grid.RowActions {
-			w.Action.Add(w.Grid.RowActions[i])
-		}
-		err = w.Action.AssignActionValues(as)
+func collectActionCode(as ActionSet) func(string, ActionCode) error {
+	return func(_ string, code ActionCode) error {
+		as[code] = Action{}
+		return nil
 	}
-	return err
 }
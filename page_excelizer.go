@@ -0,0 +1,278 @@
+package grider
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Names of the sheets holding attribute-value lines and content widgets.
+const (
+	SummarySheetName = "Summary"
+	NotesSheetName   = "Notes"
+)
+
+// maxSheetNameLen is Excel limit of the sheet name length in characters.
+const maxSheetNameLen = 31
+
+// Excelize exports the page to Excel workbook. Every grid widget of the
+// page and its tabs is written to own sheet named from the tab or widget
+// Header.Title. Lines of attribute-value widgets are written to the sheet
+// "Summary", text of content widgets to the sheet "Notes".
+//
+// Options are applied to every grid sheet.
+func (p *Page) Excelize(fname string, opts ...func(*ExcelOption)) (*DownloadResponse, error) {
+	f := excelize.NewFile()
+
+	eo := newExcelOption(opts)
+	pe := pageExporter{f: f, eo: eo, names: make(map[string]struct{})}
+	if eo.metadata {
+		// widgets titled like the metadata sheet get unique names.
+		pe.names[strings.ToLower(MetadataSheetName)] = struct{}{}
+	}
+
+	title := ""
+	if p.Header != nil {
+		title = p.Header.Title
+	}
+
+	if err := pe.widgets(title, p.Widgets); err != nil {
+		return nil, err
+	}
+
+	for i := range p.Tabs {
+		title := "Tab " + strconv.Itoa(i+1)
+		if p.Tabs[i].Header != nil && p.Tabs[i].Header.Title != "" {
+			title = p.Tabs[i].Header.Title
+		}
+		if err := pe.widgets(title, p.Tabs[i].Widgets); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := eo.writeMetadata(f); err != nil {
+		return nil, err
+	}
+
+	return newDownloadResponse(fname, f)
+}
+
+// pageExporter writes widgets of a page to the workbook sheets.
+type pageExporter struct {
+	f          *excelize.File
+	eo         *ExcelOption
+	names      map[string]struct{} // lower case names of created and reserved sheets
+	sheets     int                 // the number of created sheets
+	summary    string
+	summaryRow int
+	notes      string
+	notesRow   int
+}
+
+func (pe *pageExporter) widgets(title string, ws []Widgeter) error {
+
+	grids := 0
+	for i := range ws {
+		if w, ok := gridOfWidget(ws[i]); ok && w != nil {
+			grids++
+		}
+	}
+
+	for i := range ws {
+		wtitle := title
+		if b := BaseWidget(ws[i]); b != nil && b.Header != nil && b.Header.Title != "" {
+			wtitle = b.Header.Title
+		}
+
+		switch w := ws[i].(type) {
+		case AttrValueWidget:
+			if err := pe.lines(wtitle, w.Lines); err != nil {
+				return err
+			}
+			continue
+		case *AttrValueWidget:
+			if err := pe.lines(wtitle, w.Lines); err != nil {
+				return err
+			}
+			continue
+		case ContentWidget:
+			if err := pe.content(wtitle, w.Body); err != nil {
+				return err
+			}
+			continue
+		case *ContentWidget:
+			if err := pe.content(wtitle, w.Body); err != nil {
+				return err
+			}
+			continue
+		}
+
+		g, ok := gridOfWidget(ws[i])
+		if !ok || g == nil {
+			continue
+		}
+
+		if wtitle == title && grids > 1 {
+			wtitle = title + " " + strconv.Itoa(i+1)
+		}
+
+		sheet, err := pe.newSheet(wtitle)
+		if err != nil {
+			return err
+		}
+		if err := g.writeSheet(pe.f, sheet, pe.eo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lines writes attribute-value lines to the summary sheet. Lines are
+// preceded by the widget title.
+func (pe *pageExporter) lines(title string, ls []Line) error {
+	if len(ls) == 0 {
+		return nil
+	}
+
+	if pe.summary == "" {
+		var err error
+		if pe.summary, err = pe.newSheet(SummarySheetName); err != nil {
+			return err
+		}
+	}
+
+	if title != "" {
+		pe.summaryRow++
+		if err := pe.f.SetCellStr(pe.summary, cellName(1, pe.summaryRow), title); err != nil {
+			return err
+		}
+	}
+
+	for i := range ls {
+		pe.summaryRow++
+		if err := pe.f.SetCellStr(pe.summary, cellName(1, pe.summaryRow), ls[i].Label); err != nil {
+			return err
+		}
+
+		cell := cellName(2, pe.summaryRow)
+		if err := pe.f.SetCellStr(pe.summary, cell, ls[i].Value); err != nil {
+			return err
+		}
+
+		if ls[i].URL != "" && (ls[i].Type == LineTypeHref || ls[i].Type == LineTypeExtHref) {
+			if err := pe.f.SetCellHyperLink(pe.summary, cell, ls[i].URL, "External"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// empty line between widgets.
+	pe.summaryRow++
+	return nil
+}
+
+// content writes text of the content widget to the notes sheet.
+func (pe *pageExporter) content(title, body string) error {
+	if body == "" {
+		return nil
+	}
+
+	if pe.notes == "" {
+		var err error
+		if pe.notes, err = pe.newSheet(NotesSheetName); err != nil {
+			return err
+		}
+	}
+
+	if title != "" {
+		pe.notesRow++
+		if err := pe.f.SetCellStr(pe.notes, cellName(1, pe.notesRow), title); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range strings.Split(body, "\n") {
+		pe.notesRow++
+		if err := pe.f.SetCellStr(pe.notes, cellName(1, pe.notesRow), s); err != nil {
+			return err
+		}
+	}
+
+	pe.notesRow++
+	return nil
+}
+
+// newSheet creates a sheet with unique sanitized name and returns the name.
+func (pe *pageExporter) newSheet(title string) (string, error) {
+	name := SheetName(title)
+	if name == "" {
+		name = "Sheet"
+	}
+
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := pe.names[strings.ToLower(unique)]; !ok {
+			break
+		}
+		suffix := " (" + strconv.Itoa(i) + ")"
+		unique = strings.TrimSpace(truncateRunes(name, maxSheetNameLen-len(suffix))) + suffix
+	}
+
+	// excelize doesn't report errors here, so the result is checked.
+	if pe.sheets == 0 {
+		// the first sheet reuses the sheet created by NewFile.
+		pe.f.SetSheetName("Sheet1", unique)
+	} else if pe.f.GetSheetIndex(unique) == -1 {
+		pe.f.NewSheet(unique)
+	} else {
+		return "", errors.New("sheet " + unique + " already exists")
+	}
+	if pe.f.GetSheetIndex(unique) == -1 {
+		return "", errors.New("sheet " + unique + " is not created")
+	}
+
+	pe.sheets++
+	pe.names[strings.ToLower(unique)] = struct{}{}
+	return unique, nil
+}
+
+// SheetName converts s to a valid Excel sheet name: characters
+// []:*?/\ are removed, leading and trailing apostrophes are trimmed
+// and the name is truncated to 31 characters.
+func SheetName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '[', ']', ':', '*', '?', '/', '\\':
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Trim(strings.TrimSpace(s), "'")
+	return strings.TrimSpace(truncateRunes(s, maxSheetNameLen))
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// gridOfWidget returns the grid of the grid widget w.
+func gridOfWidget(w Widgeter) (*Grid, bool) {
+	switch w := w.(type) {
+	case GridWidget:
+		return w.Grid, true
+	case *GridWidget:
+		return w.Grid, true
+	}
+	return nil, false
+}
+
+func cellName(col, row int) string {
+	s, _ := excelize.CoordinatesToCellName(col, row)
+	return s
+}
@@ -0,0 +1,111 @@
+package grider
+
+import (
+	"reflect"
+)
+
+// Paging describes the page of the grid paginated by the server.
+// Page is 1-based. PageSizes lists page sizes the user can choose.
+type Paging struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	TotalRows  int   `json:"totalRows"`
+	TotalPages int   `json:"totalPages"`
+	PageSizes  []int `json:"pageSizes,omitempty"`
+}
+
+// NewPaging returns paging of totalRows rows. The page size not listed
+// in sizes is replaced by the first of sizes. The page is limited by
+// the number of pages, the empty result has the single page.
+func NewPaging(page, pageSize, totalRows int, sizes ...int) Paging {
+
+	if len(sizes) > 0 && !containsInt(sizes, pageSize) {
+		pageSize = sizes[0]
+	}
+	if pageSize < 1 {
+		pageSize = 1
+	}
+	if totalRows < 0 {
+		totalRows = 0
+	}
+
+	pages := (totalRows + pageSize - 1) / pageSize
+	if pages == 0 {
+		pages = 1
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if page > pages {
+		page = pages
+	}
+
+	return Paging{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalRows:  totalRows,
+		TotalPages: pages,
+		PageSizes:  sizes,
+	}
+}
+
+// Offset returns the number of rows before the page.
+func (p Paging) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// Limit returns the page size.
+func (p Paging) Limit() int {
+	return p.PageSize
+}
+
+func containsInt(a []int, n int) bool {
+	for i := range a {
+		if a[i] == n {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPaginationType sets PaginationType and NoPagination consistent
+// with it. JSON output derives NoPagination from PaginationType anyway.
+func (g *Grid) SetPaginationType(pt PaginationType) {
+	g.PaginationType = pt
+	g.NoPagination = pt == PaginationWithout
+}
+
+// SetPaging sets server pagination of the grid holding the page of
+// rows. totalRows is the number of all rows counted externally, as
+// instance by SELECT COUNT(*). See NewPaging.
+func (g *Grid) SetPaging(page, pageSize, totalRows int, sizes ...int) Paging {
+	p := NewPaging(page, pageSize, totalRows, sizes...)
+	g.Paging = &p
+	g.SetPaginationType(PaginationServer)
+	return p
+}
+
+// ApplyPage converts the page of the slice of struct src to Grid
+// like ApplySliceOfStruct does and sets server pagination. Columns
+// are created even if the page is empty.
+func (g *Grid) ApplyPage(src interface{}, page, pageSize int, sizes ...int) *Grid {
+
+	s := reflect.ValueOf(src)
+	if s.Kind() != reflect.Slice {
+		panic("ApplyPage's parameter src expected to be a slice")
+	}
+
+	p := g.SetPaging(page, pageSize, s.Len(), sizes...)
+
+	from := p.Offset()
+	if from > s.Len() {
+		from = s.Len()
+	}
+	to := from + p.Limit()
+	if to > s.Len() {
+		to = s.Len()
+	}
+
+	return g.ApplySliceOfStruct(s.Slice(from, to).Interface())
+}
@@ -0,0 +1,405 @@
+package grider
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// NullsOrder is the placement of empty values in sorted rows.
+type NullsOrder string
+
+const (
+	NullsDefault NullsOrder = ""      // last in ascending, first in descending order
+	NullsFirst   NullsOrder = "first" // first in any order
+	NullsLast    NullsOrder = "last"  // last in any order
+)
+
+// nullsOrders lists all values of NullsOrder.
+var nullsOrders = []NullsOrder{NullsDefault, NullsFirst, NullsLast}
+
+// SortKey describes sorting of the grid rows by the column.
+// Natural turns on natural sorting of numbers in text: "INV-9"
+// goes before "INV-10". Columns with the tag attribute sort=natural
+// are always sorted this way.
+type SortKey struct {
+	Column  string     `json:"column"`
+	Desc    bool       `json:"desc,omitempty"`
+	Natural bool       `json:"natural,omitempty"`
+	Nulls   NullsOrder `json:"nulls,omitempty"`
+}
+
+func (sk SortKey) String() string {
+	if sk.Desc {
+		return sk.Column + " desc"
+	}
+	return sk.Column + " asc"
+}
+
+// FilterOp is an operator of the filter condition.
+type FilterOp string
+
+const (
+	FilterEq       FilterOp = "eq"
+	FilterNe       FilterOp = "ne"
+	FilterContains FilterOp = "contains"
+	FilterBetween  FilterOp = "between" // Values holds the lower and the upper bound
+	FilterIn       FilterOp = "in"      // Values holds allowed values
+	FilterIsNull   FilterOp = "isnull"  // the cell is empty
+)
+
+// filterOps lists all values of FilterOp.
+var filterOps = []FilterOp{FilterEq, FilterNe, FilterContains, FilterBetween, FilterIn, FilterIsNull}
+
+// Filter describes a condition on the column value.
+type Filter struct {
+	Column string   `json:"column"`
+	Op     FilterOp `json:"op"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+func (f Filter) String() string {
+	switch f.Op {
+	case FilterIsNull:
+		return f.Column + " " + string(f.Op)
+	case FilterBetween, FilterIn:
+		return f.Column + " " + string(f.Op) + " " + strings.Join(f.Values, ", ")
+	}
+	return f.Column + " " + string(f.Op) + " " + f.Value
+}
+
+// match returns true if the cell value v satisfies the filter.
+// Bounds of between are compared as numbers or dates if both the cell
+// and the bound are parsed by c, otherwise as strings.
+func (f Filter) match(v string, c *Config) (bool, error) {
+	switch f.Op {
+	case FilterEq:
+		return v == f.Value, nil
+	case FilterNe:
+		return v != f.Value, nil
+	case FilterContains:
+		return strings.Contains(strings.ToLower(v), strings.ToLower(f.Value)), nil
+	case FilterIsNull:
+		return nullCell(v), nil
+	case FilterIn:
+		for _, s := range f.Values {
+			if v == s {
+				return true, nil
+			}
+		}
+		return false, nil
+	case FilterBetween:
+		if len(f.Values) != 2 {
+			return false, errors.New("filter between expects 2 values")
+		}
+		if nullCell(v) {
+			return false, nil
+		}
+		return c.compareCells(v, f.Values[0]) >= 0 && c.compareCells(v, f.Values[1]) <= 0, nil
+	}
+	return false, errors.New("unsupported filter operator " + string(f.Op))
+}
+
+// ColumnIndex returns position of the column with name or -1.
+func (g *Grid) ColumnIndex(name string) int {
+	for i := range g.Columns {
+		if g.Columns[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SortRows sorts rows by the keys. The sort is stable. Row attributes
+// (RowIDs, RowUIDs, RowActions, RowObjects, RowLinks) follow the rows.
+//
+// Numbers and dates are compared by the source values, see WithSourceValues,
+// or by values parsed from cells of the columns with type "number" and
+// "date". The type is inferred from numeric and time attributes of
+// the struct. Text is compared using collation of Config.Locale. Empty
+// cells and "-" of null.Time are empty values.
+func (g *Grid) SortRows(keys ...SortKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	cmps := make([]*sortComparer, len(keys))
+	for i := range keys {
+		c, err := g.newSortComparer(keys[i])
+		if err != nil {
+			return err
+		}
+		cmps[i] = c
+	}
+
+	idx := make([]int, len(g.Rows))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	sort.SliceStable(idx, func(a, b int) bool {
+		for _, c := range cmps {
+			if r := c.compare(idx[a], idx[b]); r != 0 {
+				return r < 0
+			}
+		}
+		return false
+	})
+
+	g.selectRows(idx)
+	return nil
+}
+
+// FilterRows keeps rows satisfying all filters. Missing cells of short
+// rows are matched as empty values.
+func (g *Grid) FilterRows(filters ...Filter) error {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	cols, err := g.filterColumns(filters)
+	if err != nil {
+		return err
+	}
+
+	cfg := g.config()
+	var idx []int
+	for row := range g.Rows {
+		ok := true
+		for i, col := range cols {
+			m, err := filters[i].match(g.cell(row, col), cfg)
+			if err != nil {
+				return err
+			}
+			if !m {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			idx = append(idx, row)
+		}
+	}
+
+	g.selectRows(idx)
+	return nil
+}
+
+// filterColumns returns positions of the filter columns. It returns
+// an error if a column is unknown or a filter is malformed.
+func (g *Grid) filterColumns(filters []Filter) ([]int, error) {
+	cols := make([]int, len(filters))
+	for i, f := range filters {
+		if cols[i] = g.ColumnIndex(f.Column); cols[i] == -1 {
+			return nil, errors.New("unknown filter column " + f.Column)
+		}
+		if !containsString(enumStrings(filterOps), string(f.Op)) {
+			return nil, errors.New("unsupported filter operator " + string(f.Op))
+		}
+		if f.Op == FilterBetween && len(f.Values) != 2 {
+			return nil, errors.New("filter between expects 2 values")
+		}
+	}
+	return cols, nil
+}
+
+// SelectRows keeps rows having ID in ids or UID in uids. Order of the
+// rows is not changed. An empty selection keeps all rows.
+func (g *Grid) SelectRows(ids []int, uids []uuid.UUID) error {
+	if len(ids) == 0 && len(uids) == 0 {
+		return nil
+	}
+
+	if len(ids) > 0 && len(g.RowIDs) != len(g.Rows) {
+		return errors.New("grid has no row ids")
+	}
+
+	if len(uids) > 0 && len(g.RowUIDs) != len(g.Rows) {
+		return errors.New("grid has no row uids")
+	}
+
+	sid := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		sid[id] = struct{}{}
+	}
+
+	suid := make(map[uuid.UUID]struct{}, len(uids))
+	for _, uid := range uids {
+		suid[uid] = struct{}{}
+	}
+
+	var idx []int
+	for row := range g.Rows {
+		if _, ok := sid[g.rowID(row)]; ok && len(ids) > 0 {
+			idx = append(idx, row)
+			continue
+		}
+		if _, ok := suid[g.rowUID(row)]; ok && len(uids) > 0 {
+			idx = append(idx, row)
+		}
+	}
+
+	g.selectRows(idx)
+	return nil
+}
+
+// cell returns the cell of the row, empty if the row is short.
+func (g *Grid) cell(row, col int) string {
+	if col < len(g.Rows[row]) {
+		return g.Rows[row][col]
+	}
+	return ""
+}
+
+func (g *Grid) rowID(row int) int {
+	if row < len(g.RowIDs) {
+		return g.RowIDs[row]
+	}
+	return 0
+}
+
+func (g *Grid) rowUID(row int) uuid.UUID {
+	if row < len(g.RowUIDs) {
+		return g.RowUIDs[row]
+	}
+	return uuid.Nil
+}
+
+// ReorderColumns moves columns listed in names to the beginning in
+// the given order. Other columns follow in their current order.
+// Unknown names are ignored.
+func (g *Grid) ReorderColumns(names ...string) {
+	idx := make([]int, 0, len(g.Columns))
+	used := make(map[int]bool, len(g.Columns))
+	for _, name := range names {
+		if i := g.ColumnIndex(name); i != -1 && !used[i] {
+			idx = append(idx, i)
+			used[i] = true
+		}
+	}
+
+	for i := range g.Columns {
+		if !used[i] {
+			idx = append(idx, i)
+		}
+	}
+
+	g.selectColumns(idx)
+}
+
+// selectRows replaces rows and row attributes by the elements
+// at positions idx. New slices are allocated, so the grid's slices
+// shared with other grids are not modified.
+func (g *Grid) selectRows(idx []int) {
+	n := len(g.Rows)
+
+	rows := make([][]string, len(idx))
+	for i, j := range idx {
+		rows[i] = g.Rows[j]
+	}
+	g.Rows = rows
+
+	if len(g.RowObjects) == n {
+		res := make([]interface{}, len(idx))
+		for i, j := range idx {
+			res[i] = g.RowObjects[j]
+		}
+		g.RowObjects = res
+	}
+
+	if len(g.RowIDs) == n {
+		res := make([]int, len(idx))
+		for i, j := range idx {
+			res[i] = g.RowIDs[j]
+		}
+		g.RowIDs = res
+	}
+
+	if len(g.RowUIDs) == n {
+		res := make([]uuid.UUID, len(idx))
+		for i, j := range idx {
+			res[i] = g.RowUIDs[j]
+		}
+		g.RowUIDs = res
+	}
+
+	if len(g.RowActions) == n {
+		res := make([][]ActionCode, len(idx))
+		for i, j := range idx {
+			res[i] = g.RowActions[j]
+		}
+		g.RowActions = res
+	}
+
+	if len(g.RowLinks) == n {
+		res := make([][]*Link, len(idx))
+		for i, j := range idx {
+			res[i] = g.RowLinks[j]
+		}
+		g.RowLinks = res
+	}
+
+	if len(g.RowHighlights) == n {
+		res := make([][]Highlight, len(idx))
+		for i, j := range idx {
+			res[i] = g.RowHighlights[j]
+		}
+		g.RowHighlights = res
+	}
+
+	if len(g.values) == n {
+		res := make([][]interface{}, len(idx))
+		for i, j := range idx {
+			res[i] = g.values[j]
+		}
+		g.values = res
+	}
+}
+
+// selectColumns replaces columns and cells of the rows by the elements
+// at positions idx. New slices are allocated.
+func (g *Grid) selectColumns(idx []int) {
+	cols := make([]Column, len(idx))
+	for i, j := range idx {
+		cols[i] = g.Columns[j]
+	}
+	g.Columns = cols
+
+	rows := make([][]string, len(g.Rows))
+	for r := range g.Rows {
+		rows[r] = make([]string, len(idx))
+		for i, j := range idx {
+			if j < len(g.Rows[r]) {
+				rows[r][i] = g.Rows[r][j]
+			}
+		}
+	}
+	g.Rows = rows
+
+	if g.RowLinks != nil {
+		links := make([][]*Link, len(g.RowLinks))
+		for r := range g.RowLinks {
+			links[r] = make([]*Link, len(idx))
+			for i, j := range idx {
+				if j < len(g.RowLinks[r]) {
+					links[r][i] = g.RowLinks[r][j]
+				}
+			}
+		}
+		g.RowLinks = links
+	}
+
+	g.remapHighlights(idx)
+
+	if g.values != nil {
+		values := make([][]interface{}, len(g.values))
+		for r := range g.values {
+			values[r] = permuteValues(g.values[r], idx)
+		}
+		g.values = values
+	}
+	g.BuildHeaderGroups()
+}
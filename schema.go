@@ -0,0 +1,613 @@
+package grider
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Generation of TypeScript definitions and JSON Schema describing
+// the grid/page protocol. The definitions are built by reflection
+// over the package types and their json tags.
+//
+// Widgets are represented as a discriminated union named Widget
+// where the attribute "type" holds widget type name. ContentWidget
+// hides it by the body type ("text", "html", "markdown"), so its
+// discriminator values are the body types. Discriminator values of
+// widgets are expected to be distinct. Application defined widgets
+// and string enums can be added with RegisterWidget and RegisterEnum.
+//
+// Attributes of the embedded *Widget are optional, as they are omitted
+// if the pointer is nil, except the discriminator.
+
+var schemaRegistry = struct {
+	sync.Mutex
+	widgets []Widgeter
+	enums   map[reflect.Type][]string
+}{
+	widgets: []Widgeter{
+		AttrValueWidget{},
+		MediaWidget{},
+		GridWidget{},
+		ContentWidget{},
+		LazyWidget{},
+		EmptyWidget{},
+	},
+	enums: map[reflect.Type][]string{
+		reflect.TypeOf(WidgetType(0)):      stringerValues(func(i int) string { return WidgetType(i).String() }),
+		reflect.TypeOf(ContentBodyType(0)): stringerValues(func(i int) string { return ContentBodyType(i).String() }),
+		reflect.TypeOf(PaginationType(0)):  stringerValues(func(i int) string { return PaginationType(i).String() }),
+		reflect.TypeOf(FilterOp("")):       enumStrings(filterOps),
+		reflect.TypeOf(NullsOrder("")):     enumStrings(nullsOrders),
+		reflect.TypeOf(FilterType("")):     enumStrings(filterTypes),
+		reflect.TypeOf(LineType("")):       enumStrings(lineTypes),
+	},
+}
+
+// maxStringerValue limits values of integer enums checked by stringerValues.
+const maxStringerValue = 64
+
+// stringerValues returns non empty names of integer enum values
+// 0..maxStringerValue, so the list follows the String method.
+func stringerValues(name func(int) string) []string {
+	var res []string
+	for i := 0; i <= maxStringerValue; i++ {
+		if s := name(i); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// enumStrings returns values of the slice of string enum values.
+func enumStrings(list interface{}) []string {
+	v := reflect.ValueOf(list)
+	res := make([]string, v.Len())
+	for i := range res {
+		res[i] = v.Index(i).String()
+	}
+	return res
+}
+
+// RegisterWidget adds application defined widget to the generated
+// definitions. The widget is expected to embed *Widget.
+func RegisterWidget(w Widgeter) {
+	schemaRegistry.Lock()
+	defer schemaRegistry.Unlock()
+	schemaRegistry.widgets = append(schemaRegistry.widgets, w)
+}
+
+// RegisterEnum registers all string values of the type of sample.
+// The type is expected to be marshalled to JSON as a string.
+func RegisterEnum(sample interface{}, values ...string) {
+	schemaRegistry.Lock()
+	defer schemaRegistry.Unlock()
+	schemaRegistry.enums[reflect.TypeOf(sample)] = values
+}
+
+var (
+	widgeterType      = reflect.TypeOf((*Widgeter)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// fieldMarshalers lists struct types implementing json.Marshaler
+	// that are written by their fields.
+	fieldMarshalers = map[reflect.Type]bool{reflect.TypeOf(Grid{}): true}
+)
+
+// protocolField describes a struct attribute as it's visible in JSON.
+type protocolField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+	depth     int
+}
+
+// protocolFields returns attributes of the struct t following
+// encoding/json rules: embedded structs are inlined, an attribute
+// with lower depth hides attributes with the same name. Attributes
+// of embedded pointers are omitted if the pointer is nil, so they are
+// marked as omitempty.
+func protocolFields(t reflect.Type) []protocolField {
+	var res []protocolField
+	pos := make(map[string]int)
+
+	var walk func(t reflect.Type, depth int, optional bool)
+	walk = func(t reflect.Type, depth int, optional bool) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			name, opts := tag, ""
+			if k := strings.Index(tag, ","); k >= 0 {
+				name, opts = tag[:k], tag[k:]
+			}
+
+			if sf.Anonymous {
+				et := sf.Type
+				if et.Kind() == reflect.Ptr {
+					et = et.Elem()
+				}
+				if name == "" && et.Kind() == reflect.Struct {
+					walk(et, depth+1, optional || sf.Type.Kind() == reflect.Ptr)
+					continue
+				}
+			}
+
+			if sf.PkgPath != "" {
+				continue
+			}
+
+			if name == "" {
+				name = sf.Name
+			}
+
+			f := protocolField{
+				name:      name,
+				typ:       sf.Type,
+				omitempty: optional || strings.Contains(opts, ",omitempty"),
+				depth:     depth,
+			}
+
+			if j, ok := pos[name]; ok {
+				if res[j].depth > depth {
+					res[j] = f
+				}
+				continue
+			}
+			pos[name] = len(res)
+			res = append(res, f)
+		}
+	}
+
+	walk(t, 0, false)
+	return res
+}
+
+// protocolType describes a named type emitted to the generated output.
+type protocolType struct {
+	name   string
+	typ    reflect.Type
+	widget []string // discriminator values, if the type is a widget.
+	enum   []string
+}
+
+// schemaGen holds named types discovered from the protocol roots.
+type schemaGen struct {
+	types   []protocolType
+	seen    map[reflect.Type]bool
+	enums   map[reflect.Type][]string
+	widgets []protocolType
+}
+
+func newSchemaGen() (*schemaGen, error) {
+	schemaRegistry.Lock()
+	defer schemaRegistry.Unlock()
+
+	g := schemaGen{
+		seen:  make(map[reflect.Type]bool),
+		enums: make(map[reflect.Type][]string, len(schemaRegistry.enums)),
+	}
+	for k, v := range schemaRegistry.enums {
+		g.enums[k] = v
+	}
+
+	disc := make(map[string]string)
+	g.collect(reflect.TypeOf(Page{}))
+	g.collect(reflect.TypeOf(Grid{}))
+	for _, w := range schemaRegistry.widgets {
+		t := reflect.TypeOf(w)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		g.seen[t] = true
+		pt := protocolType{name: t.Name(), typ: t, widget: g.discriminator(t, w)}
+		if pt.widget == nil {
+			return nil, fmt.Errorf("widget %s has no string enum attribute \"type\"", t.Name())
+		}
+		for _, v := range pt.widget {
+			if other, ok := disc[v]; ok {
+				return nil, fmt.Errorf("widgets %s and %s have the same type %q", other, t.Name(), v)
+			}
+			disc[v] = t.Name()
+		}
+		g.widgets = append(g.widgets, pt)
+		g.collectFields(t)
+	}
+	return &g, nil
+}
+
+// discriminator returns values of the attribute "type" of the widget
+// type t, nil if the attribute isn't the enum.
+func (g *schemaGen) discriminator(t reflect.Type, w Widgeter) []string {
+	for _, f := range protocolFields(t) {
+		if f.name != "type" {
+			continue
+		}
+		if f.typ == reflect.TypeOf(WidgetType(0)) {
+			return []string{w.WidgetType().String()}
+		}
+		return g.enums[f.typ]
+	}
+	return nil
+}
+
+// collect registers t and all named types reachable from t.
+func (g *schemaGen) collect(t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if g.seen[t] {
+		return
+	}
+
+	if ev, ok := g.enums[t]; ok {
+		g.seen[t] = true
+		g.types = append(g.types, protocolType{name: t.Name(), typ: t, enum: ev})
+		return
+	}
+
+	if t == widgeterType || t == timeType || t == rawMessageType || isMarshaler(t) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if t.Name() != "" {
+			g.seen[t] = true
+			g.types = append(g.types, protocolType{name: t.Name(), typ: t})
+		}
+		if t.Kind() == reflect.Map {
+			g.collect(t.Key())
+		}
+		g.collect(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			g.collectFields(t)
+			return
+		}
+		g.seen[t] = true
+		g.types = append(g.types, protocolType{name: t.Name(), typ: t})
+		g.collectFields(t)
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if t.Name() != "" && t.PkgPath() != "" {
+			g.seen[t] = true
+			g.types = append(g.types, protocolType{name: t.Name(), typ: t})
+		}
+	}
+}
+
+func (g *schemaGen) collectFields(t reflect.Type) {
+	for _, f := range protocolFields(t) {
+		g.collect(f.typ)
+	}
+}
+
+func isMarshaler(t reflect.Type) bool {
+	if fieldMarshalers[t] || t.Kind() == reflect.Ptr && fieldMarshalers[t.Elem()] {
+		return false
+	}
+	return t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+func isTextMarshaler(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// nullable returns true if zero value of t is marshalled as null.
+func nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		return true
+	case reflect.Slice:
+		return t != rawMessageType
+	}
+	return false
+}
+
+// GenerateTypeScript writes TypeScript definitions of the grid/page
+// protocol to w.
+func GenerateTypeScript(w io.Writer) error {
+	g, err := newSchemaGen()
+	if err != nil {
+		return err
+	}
+	b := &strings.Builder{}
+
+	b.WriteString("// Code generated by grider. DO NOT EDIT.\n")
+
+	for _, pt := range g.types {
+		b.WriteString("\n")
+		switch {
+		case pt.enum != nil:
+			lit := make([]string, len(pt.enum))
+			for i := range pt.enum {
+				lit[i] = fmt.Sprintf("%q", pt.enum[i])
+			}
+			fmt.Fprintf(b, "export type %s = %s;\n", pt.name, strings.Join(lit, " | "))
+		case pt.typ.Kind() == reflect.Struct:
+			g.tsInterface(b, pt)
+		default:
+			fmt.Fprintf(b, "export type %s = %s;\n", pt.name, g.definition(pt.typ, g.tsBaseType))
+		}
+	}
+
+	names := make([]string, len(g.widgets))
+	for i, pt := range g.widgets {
+		b.WriteString("\n")
+		g.tsInterface(b, pt)
+		names[i] = pt.name
+	}
+
+	fmt.Fprintf(b, "\nexport type Widget =\n  | %s;\n", strings.Join(names, "\n  | "))
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func (g *schemaGen) tsInterface(b *strings.Builder, pt protocolType) {
+	fmt.Fprintf(b, "export interface %s {\n", pt.name)
+	for _, f := range protocolFields(pt.typ) {
+		if pt.widget != nil && f.name == "type" {
+			lit := make([]string, len(pt.widget))
+			for i := range pt.widget {
+				lit[i] = fmt.Sprintf("%q", pt.widget[i])
+			}
+			fmt.Fprintf(b, "  type: %s;\n", strings.Join(lit, " | "))
+			continue
+		}
+		opt := ""
+		if f.omitempty {
+			opt = "?"
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", f.name, opt, g.tsType(f.typ, f.omitempty))
+	}
+	b.WriteString("}\n")
+}
+
+// tsType returns TypeScript type expression of t. If omitempty is false
+// and zero value of t is marshalled as null, the type is extended by null.
+func (g *schemaGen) tsType(t reflect.Type, omitempty bool) string {
+	s := g.tsBaseType(t)
+	if !omitempty && nullable(t) && s != "unknown" {
+		s += " | null"
+	}
+	return s
+}
+
+func (g *schemaGen) tsBaseType(t reflect.Type) string {
+
+	if _, ok := g.enums[t]; ok {
+		return t.Name()
+	}
+
+	switch {
+	case t == widgeterType:
+		return "Widget"
+	case t == timeType:
+		return "string"
+	case t == rawMessageType:
+		return "unknown"
+	case isTextMarshaler(t):
+		return "string"
+	case isMarshaler(t):
+		return "unknown"
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.tsBaseType(t.Elem())
+	case reflect.Interface:
+		return "unknown"
+	case reflect.Bool:
+		return namedOr(g, t, "boolean")
+	case reflect.String:
+		return namedOr(g, t, "string")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return namedOr(g, t, "number")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		if g.seen[t] {
+			return t.Name()
+		}
+		e := g.tsType(t.Elem(), true)
+		if strings.Contains(e, " ") {
+			e = "(" + e + ")"
+		}
+		return e + "[]"
+	case reflect.Map:
+		if g.seen[t] {
+			return t.Name()
+		}
+		return "Record<string, " + g.tsType(t.Elem(), true) + ">"
+	case reflect.Struct:
+		if g.seen[t] {
+			return t.Name()
+		}
+		b := &strings.Builder{}
+		b.WriteString("{ ")
+		for _, f := range protocolFields(t) {
+			opt := ""
+			if f.omitempty {
+				opt = "?"
+			}
+			fmt.Fprintf(b, "%s%s: %s; ", f.name, opt, g.tsType(f.typ, f.omitempty))
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	return "unknown"
+}
+
+// definition returns the type expression of the named type t itself
+// instead of a reference to t.
+func (g *schemaGen) definition(t reflect.Type, f interface{}) interface{} {
+	delete(g.seen, t)
+	defer func() { g.seen[t] = true }()
+
+	switch f := f.(type) {
+	case func(reflect.Type) string:
+		return f(t)
+	case func(reflect.Type) map[string]interface{}:
+		return f(t)
+	}
+	return nil
+}
+
+func namedOr(g *schemaGen, t reflect.Type, s string) string {
+	if g.seen[t] {
+		return t.Name()
+	}
+	return s
+}
+
+// GenerateJSONSchema writes JSON Schema (draft 2020-12) of the grid/page
+// protocol to w. Root schema refers to Page, other types are placed
+// in $defs.
+func GenerateJSONSchema(w io.Writer) error {
+	g, err := newSchemaGen()
+	if err != nil {
+		return err
+	}
+
+	defs := make(map[string]interface{}, len(g.types)+len(g.widgets)+1)
+	for _, pt := range g.types {
+		switch {
+		case pt.enum != nil:
+			defs[pt.name] = map[string]interface{}{"type": "string", "enum": pt.enum}
+		case pt.typ.Kind() == reflect.Struct:
+			defs[pt.name] = g.jsObject(pt)
+		default:
+			defs[pt.name] = g.definition(pt.typ, g.jsBaseType)
+		}
+	}
+
+	var union []interface{}
+	for _, pt := range g.widgets {
+		defs[pt.name] = g.jsObject(pt)
+		union = append(union, ref(pt.name))
+	}
+	defs["Widget"] = map[string]interface{}{"oneOf": union}
+
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "https://github.com/golangkit/grider/schema.json",
+		"$ref":    "#/$defs/Page",
+		"$defs":   defs,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+func (g *schemaGen) jsObject(pt protocolType) map[string]interface{} {
+	props := make(map[string]interface{})
+	required := []string{}
+	for _, f := range protocolFields(pt.typ) {
+		disc := pt.widget != nil && f.name == "type"
+		switch {
+		case disc && len(pt.widget) == 1:
+			props[f.name] = map[string]interface{}{"const": pt.widget[0]}
+		case disc:
+			props[f.name] = map[string]interface{}{"enum": pt.widget}
+		default:
+			props[f.name] = g.jsType(f.typ, f.omitempty)
+		}
+		if !f.omitempty || disc {
+			required = append(required, f.name)
+		}
+	}
+	sort.Strings(required)
+
+	res := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		res["required"] = required
+	}
+	return res
+}
+
+func (g *schemaGen) jsType(t reflect.Type, omitempty bool) map[string]interface{} {
+	s := g.jsBaseType(t)
+	if !omitempty && nullable(t) && len(s) > 0 {
+		return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+	}
+	return s
+}
+
+func (g *schemaGen) jsBaseType(t reflect.Type) map[string]interface{} {
+
+	if _, ok := g.enums[t]; ok {
+		return ref(t.Name())
+	}
+
+	switch {
+	case t == widgeterType:
+		return ref("Widget")
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case isTextMarshaler(t):
+		return map[string]interface{}{"type": "string"}
+	case isMarshaler(t):
+		return map[string]interface{}{}
+	}
+
+	if g.seen[t] && t.Kind() != reflect.Ptr {
+		return ref(t.Name())
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.jsBaseType(t.Elem())
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.jsType(t.Elem(), true)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.jsType(t.Elem(), true)}
+	case reflect.Struct:
+		return g.jsObject(protocolType{typ: t})
+	}
+	return map[string]interface{}{}
+}
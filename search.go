@@ -0,0 +1,216 @@
+package grider
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Highlight marks matches of the search in the cell of the column
+// Column. Ranges hold start and end (exclusive) rune offsets in the cell.
+type Highlight struct {
+	Column int      `json:"column"`
+	Ranges [][2]int `json:"ranges"`
+}
+
+// SearchResult is the row matching the search query.
+type SearchResult struct {
+	Row        int
+	Highlights []Highlight
+}
+
+// Search finds rows where every word of the query is found in any
+// searchable column. Columns are searchable if they are not hidden and
+// have no tag attribute search=false. Search ignores case and accents:
+// "cafe" finds "Café".
+func (g *Grid) Search(query string) []SearchResult {
+
+	tokens := searchTokens(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var cols []int
+	for i := range g.Columns {
+		if !g.Columns[i].Hidden && !g.Columns[i].NoSearch {
+			cols = append(cols, i)
+		}
+	}
+
+	var res []SearchResult
+	found := make([]bool, len(tokens))
+	for row := range g.Rows {
+		for i := range found {
+			found[i] = false
+		}
+
+		var hs []Highlight
+		for _, col := range cols {
+			if col >= len(g.Rows[row]) {
+				continue
+			}
+
+			folded, pos := foldText(g.Rows[row][col])
+			var ranges [][2]int
+			for i, t := range tokens {
+				for _, start := range indexAll(folded, t) {
+					end := start + len(t) - 1
+					ranges = append(ranges, [2]int{pos[start], pos[end] + 1})
+					found[i] = true
+				}
+			}
+
+			if len(ranges) > 0 {
+				hs = append(hs, Highlight{Column: col, Ranges: mergeRanges(ranges)})
+			}
+		}
+
+		if allTrue(found) {
+			res = append(res, SearchResult{Row: row, Highlights: hs})
+		}
+	}
+	return res
+}
+
+// ApplySearch keeps rows found by Search and sets RowHighlights.
+// An empty query keeps all rows.
+func (g *Grid) ApplySearch(query string) {
+	if len(searchTokens(query)) == 0 {
+		return
+	}
+
+	found := g.Search(query)
+	idx := make([]int, len(found))
+	for i := range found {
+		idx[i] = found[i].Row
+	}
+	g.selectRows(idx)
+
+	g.RowHighlights = make([][]Highlight, len(found))
+	for i := range found {
+		g.RowHighlights[i] = found[i].Highlights
+	}
+}
+
+// remapHighlights updates column indexes of RowHighlights after
+// columns at positions idx became columns 0, 1, ...
+func (g *Grid) remapHighlights(idx []int) {
+	if g.RowHighlights == nil {
+		return
+	}
+
+	pos := make(map[int]int, len(idx))
+	for i, j := range idx {
+		pos[j] = i
+	}
+
+	res := make([][]Highlight, len(g.RowHighlights))
+	for r, hs := range g.RowHighlights {
+		for _, h := range hs {
+			if i, ok := pos[h.Column]; ok {
+				h.Column = i
+				res[r] = append(res[r], h)
+			}
+		}
+	}
+	g.RowHighlights = res
+}
+
+// searchTokens returns distinct folded words of the query.
+func searchTokens(query string) [][]rune {
+	var res [][]rune
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(query) {
+		f, _ := foldText(w)
+		if len(f) == 0 || seen[string(f)] {
+			continue
+		}
+		seen[string(f)] = true
+		res = append(res, f)
+	}
+	return res
+}
+
+// foldRunes maps letters not decomposed by NFD to their base letters.
+var foldRunes = map[rune]string{
+	'ß': "ss",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ħ': "h",
+	'ı': "i",
+	'æ': "ae",
+	'œ': "oe",
+}
+
+// foldText converts s to lower case without diacritical marks.
+// pos[i] is the rune offset in s of the folded rune i.
+func foldText(s string) (folded []rune, pos []int) {
+	i := 0
+	for _, r := range s {
+		lr := unicode.ToLower(r)
+		f, ok := foldRunes[lr]
+		if !ok {
+			f = norm.NFD.String(string(lr))
+		}
+
+		for _, fr := range f {
+			if unicode.Is(unicode.Mn, fr) {
+				continue
+			}
+			folded = append(folded, fr)
+			pos = append(pos, i)
+		}
+		i++
+	}
+	return folded, pos
+}
+
+// indexAll returns start positions of all occurrences of sub in s.
+func indexAll(s, sub []rune) []int {
+	var res []int
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// mergeRanges sorts ranges and merges the overlapping ones.
+func mergeRanges(ranges [][2]int) [][2]int {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	res := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &res[len(res)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+func allTrue(b []bool) bool {
+	for i := range b {
+		if !b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package grider
+
+import (
+	"errors"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// sortComparer compares rows of the grid by the sort key.
+type sortComparer struct {
+	g     *Grid
+	key   SortKey
+	col   int
+	typed bool // cells are parsed as numbers and dates
+	coll  *collate.Collator
+	cfg   *Config
+}
+
+func (g *Grid) newSortComparer(key SortKey) (*sortComparer, error) {
+	col := g.ColumnIndex(key.Column)
+	if col == -1 {
+		return nil, errors.New("unknown sort column " + key.Column)
+	}
+
+	if !containsString(enumStrings(nullsOrders), string(key.Nulls)) {
+		return nil, errors.New("unsupported nulls order " + string(key.Nulls))
+	}
+
+	cfg := g.config()
+	c := &g.Columns[col]
+	return &sortComparer{
+		g:     g,
+		key:   key,
+		col:   col,
+		typed: c.Type == "number" || c.Type == "date",
+		coll:  cfg.collator(key.Natural || c.Sort == "natural"),
+		cfg:   cfg,
+	}, nil
+}
+
+// collator returns collator of the config locale. The collator isn't
+// safe for concurrent use.
+func (c *Config) collator(natural bool) *collate.Collator {
+	tag := language.Und
+	if c.Locale != "" {
+		if t, err := language.Parse(c.Locale); err == nil {
+			tag = t
+		}
+	}
+
+	if natural {
+		return collate.New(tag, collate.Numeric)
+	}
+	return collate.New(tag)
+}
+
+// compare returns the order of the rows a and b taking into account
+// direction and placement of empty values.
+func (sc *sortComparer) compare(a, b int) int {
+	va, vb := sc.g.sourceValue(a, sc.col), sc.g.sourceValue(b, sc.col)
+
+	if va == nil || vb == nil {
+		if va == nil && vb == nil {
+			return 0
+		}
+
+		// by default empty values are greater than any value.
+		r := 1
+		if va != nil {
+			r = -1
+		}
+
+		switch sc.key.Nulls {
+		case NullsFirst:
+			return -r
+		case NullsLast:
+			return r
+		}
+		if sc.key.Desc {
+			return -r
+		}
+		return r
+	}
+
+	r := sc.compareValues(a, b, va, vb)
+	if sc.key.Desc {
+		return -r
+	}
+	return r
+}
+
+// compareValues compares not empty values of the rows a and b.
+func (sc *sortComparer) compareValues(a, b int, va, vb interface{}) int {
+	if r, ok := compareTyped(va, vb); ok {
+		return r
+	}
+
+	ca, cb := sc.g.cell(a, sc.col), sc.g.cell(b, sc.col)
+	if sc.typed {
+		if r, ok := sc.cfg.compareParsed(ca, cb); ok {
+			return r
+		}
+	}
+	return sc.coll.CompareString(ca, cb)
+}
@@ -0,0 +1,194 @@
+package grider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// sqlKind describes how values of the result set column are scanned
+// and presented.
+type sqlKind int
+
+const (
+	sqlString sqlKind = iota
+	sqlInt
+	sqlFloat
+	sqlBool
+	sqlTime
+	sqlDate
+	sqlDecimal
+)
+
+// sqlColumnKind detects kind of the column by the scan type reported
+// by the driver and the database type name. Decimals are scanned
+// as text to keep their precision. DATE columns are dates only if
+// the driver scans them as time, as instance MySQL driver without
+// parseTime returns bytes.
+func sqlColumnKind(ct *sql.ColumnType) sqlKind {
+
+	st := ct.ScanType()
+	isTime := st == reflect.TypeOf(sql.NullTime{}) || st == reflect.TypeOf(time.Time{})
+
+	switch strings.ToUpper(ct.DatabaseTypeName()) {
+	case "DATE":
+		if isTime {
+			return sqlDate
+		}
+	case "NUMERIC", "DECIMAL", "MONEY":
+		return sqlDecimal
+	}
+
+	if st == nil {
+		return sqlString
+	}
+	if isTime {
+		return sqlTime
+	}
+
+	switch st {
+	case reflect.TypeOf(sql.NullInt64{}), reflect.TypeOf(sql.NullInt32{}):
+		return sqlInt
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return sqlFloat
+	case reflect.TypeOf(sql.NullBool{}):
+		return sqlBool
+	}
+
+	for st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+
+	switch st.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sqlInt
+	case reflect.Float32, reflect.Float64:
+		return sqlFloat
+	case reflect.Bool:
+		return sqlBool
+	}
+	return sqlString
+}
+
+// scanDest returns a new destination for Scan of the column kind.
+func (k sqlKind) scanDest() interface{} {
+	switch k {
+	case sqlInt:
+		return &sql.NullInt64{}
+	case sqlFloat:
+		return &sql.NullFloat64{}
+	case sqlBool:
+		return &sql.NullBool{}
+	case sqlTime, sqlDate:
+		return &sql.NullTime{}
+	}
+	return &sql.NullString{}
+}
+
+// value converts the scanned destination to the type formatted
+// the same way as the struct attributes. Decimals are returned
+// as json.Number, so they are compared as numbers.
+func (k sqlKind) value(dest interface{}) interface{} {
+	if d, ok := dest.(*sql.NullString); ok && k == sqlDecimal && d.Valid {
+		return json.Number(d.String)
+	}
+
+	switch d := dest.(type) {
+	case *sql.NullInt64:
+		return null.NewInt(d.Int64, d.Valid)
+	case *sql.NullFloat64:
+		return null.NewFloat(d.Float64, d.Valid)
+	case *sql.NullBool:
+		return null.NewBool(d.Bool, d.Valid)
+	case *sql.NullTime:
+		return null.NewTime(d.Time, d.Valid)
+	case *sql.NullString:
+		return null.NewString(d.String, d.Valid)
+	}
+	return nil
+}
+
+// column returns type and align of the column kind.
+func (k sqlKind) column() (typ, align string) {
+	switch k {
+	case sqlInt, sqlFloat, sqlDecimal:
+		return "number", "right"
+	case sqlBool:
+		return "bool", "center"
+	case sqlTime, sqlDate:
+		return "date", ""
+	}
+	return "", ""
+}
+
+// ApplySQLRows reads columns and rows of the result set to the grid.
+// Columns are created from rows.ColumnTypes(): numeric columns are right
+// aligned, nullable and time values are formatted the same way as
+// attributes of the struct passed to ApplySliceOfStruct. NUMERIC, DECIMAL
+// and MONEY values are written as returned by the database.
+//
+// Optional meta supplies column attributes (title, href, hidden, order, ...)
+// by the result set column name. ApplySQLRows does not close rows.
+func (g *Grid) ApplySQLRows(rows *sql.Rows, meta map[string]Column) error {
+
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	kinds := make([]sqlKind, len(cts))
+	g.Columns = make([]Column, len(cts))
+	for i, ct := range cts {
+		kinds[i] = sqlColumnKind(ct)
+		typ, align := kinds[i].column()
+
+		c := meta[ct.Name()]
+		c.Name = ct.Name()
+		if c.Title == "" {
+			c.Title = g.columnTitle(c.Name)
+		}
+		if c.Type == "" {
+			c.Type = typ
+		}
+		if c.Align == "" {
+			c.Align = align
+		}
+		g.Columns[i] = c
+	}
+	g.applyOverrides()
+	perm := g.orderColumns()
+
+	cfg := g.config()
+	dest := make([]interface{}, len(cts))
+	for rows.Next() {
+		for i := range kinds {
+			dest[i] = kinds[i].scanDest()
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		row := make([]string, len(cts))
+		values := make([]interface{}, len(cts))
+		for i := range kinds {
+			layout := ""
+			if kinds[i] == sqlDate {
+				layout = "date"
+			}
+			values[i] = kinds[i].value(dest[i])
+			row[i] = cfg.formatAttribute(reflect.ValueOf(values[i]), layout)
+		}
+		g.Rows = append(g.Rows, permute(row, perm))
+		if g.option.sourceValues {
+			g.values = append(g.values, permuteValues(values, perm))
+		}
+	}
+
+	return rows.Err()
+}
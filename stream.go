@@ -0,0 +1,379 @@
+package grider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// RowArray is the set of the row arrays of the grid.
+type RowArray uint8
+
+const (
+	RowObjectsArray RowArray = 1 << iota
+	RowIDsArray
+	RowUIDsArray
+	RowActionsArray
+	RowLinksArray
+	RowHighlightsArray
+)
+
+// StreamRow is the row written by StreamJSON: cells and the elements
+// of the row arrays of the grid. The row array is written if any row
+// has not zero element or marks the array in Arrays, so arrays holding
+// only zero values, as instance RowIDs of new rows, are kept.
+type StreamRow struct {
+	Cells      []string
+	Object     interface{}
+	ID         int
+	UID        uuid.UUID
+	Actions    []ActionCode
+	Links      []*Link
+	Highlights []Highlight
+	Arrays     RowArray
+}
+
+// RowIterator is the source of rows of StreamJSON. It's used like
+// sql.Rows: Next advances to the next row, Row returns it and Err
+// returns the error stopped the iteration.
+type RowIterator interface {
+	Next() bool
+	Row() StreamRow
+	Err() error
+}
+
+type gridRows struct {
+	g   *Grid
+	row int
+}
+
+// Iter returns the iterator over rows of the grid.
+func (g *Grid) Iter() RowIterator {
+	return &gridRows{g: g, row: -1}
+}
+
+func (it *gridRows) Next() bool {
+	it.row++
+	return it.row < len(it.g.Rows)
+}
+
+func (it *gridRows) Row() StreamRow {
+	g, i := it.g, it.row
+	res := StreamRow{Cells: g.Rows[i]}
+	if i < len(g.RowObjects) {
+		res.Object = g.RowObjects[i]
+		res.Arrays |= RowObjectsArray
+	}
+	if i < len(g.RowIDs) {
+		res.ID = g.RowIDs[i]
+		res.Arrays |= RowIDsArray
+	}
+	if i < len(g.RowUIDs) {
+		res.UID = g.RowUIDs[i]
+		res.Arrays |= RowUIDsArray
+	}
+	if i < len(g.RowActions) {
+		res.Actions = g.RowActions[i]
+		res.Arrays |= RowActionsArray
+	}
+	if i < len(g.RowLinks) {
+		res.Links = g.RowLinks[i]
+		res.Arrays |= RowLinksArray
+	}
+	if i < len(g.RowHighlights) {
+		res.Highlights = g.RowHighlights[i]
+		res.Arrays |= RowHighlightsArray
+	}
+	return res
+}
+
+func (it *gridRows) Err() error {
+	return nil
+}
+
+type chanRows struct {
+	ctx  context.Context
+	rows <-chan StreamRow
+	errc <-chan error
+	row  StreamRow
+	err  error
+}
+
+// ChanRows returns the iterator over rows received from the channel
+// rows until it's closed. Then the error is received from errc if it
+// isn't nil: the producer sends the error or nil, or closes errc.
+//
+// The iteration stops with ctx.Err() when ctx is done. The producer
+// is expected to stop sending on ctx.Done() too, so the caller cancels
+// ctx after the stream is written, even if writing fails:
+//
+//	ctx, cancel := context.WithCancel(r.Context())
+//	defer cancel()
+//	go produce(ctx, rows, errc)
+//	err := g.StreamJSON(w, grider.ChanRows(ctx, rows, errc))
+func ChanRows(ctx context.Context, rows <-chan StreamRow, errc <-chan error) RowIterator {
+	return &chanRows{ctx: ctx, rows: rows, errc: errc}
+}
+
+func (it *chanRows) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	select {
+	case row, ok := <-it.rows:
+		if ok {
+			it.row = row
+			return true
+		}
+	case <-it.ctx.Done():
+		it.err = it.ctx.Err()
+		return false
+	}
+
+	if it.errc != nil {
+		select {
+		case it.err = <-it.errc:
+		case <-it.ctx.Done():
+			it.err = it.ctx.Err()
+		}
+		it.errc = nil
+	}
+	return false
+}
+
+func (it *chanRows) Row() StreamRow {
+	return it.row
+}
+
+func (it *chanRows) Err() error {
+	return it.err
+}
+
+// StructRow converts the struct (or pointer to struct) v to the row of
+// the grid. Columns of the grid are expected to be created by
+// ApplySliceOfStruct from the slice of the same struct type, that may be
+// empty. Object is set if the struct has Object method.
+func (g *Grid) StructRow(v interface{}) StreamRow {
+
+	s := reflect.ValueOf(v)
+	if s.Kind() == reflect.Ptr {
+		s = s.Elem()
+	} else {
+		// Object method may have the pointer receiver.
+		p := reflect.New(s.Type())
+		p.Elem().Set(s)
+		s = p.Elem()
+	}
+
+	cells, _ := g.convertStructValues("", s)
+	res := StreamRow{Cells: permute(cells, g.perm)}
+
+	ofunc := s.Addr().MethodByName("Object")
+	if ofunc.IsValid() && !ofunc.IsZero() {
+		res.Object = ofunc.Call([]reflect.Value{})[0].Interface()
+		res.Arrays |= RowObjectsArray
+	}
+	return res
+}
+
+// StreamJSON writes JSON representation of the grid with rows read from
+// it. Rows and row arrays of the grid are ignored. Rows are written
+// while they are read, row arrays are written after rows and are spooled
+// to temporary files, so memory usage doesn't depend on the number
+// of rows.
+//
+// The output is the same as of JSON if the grid holds the rows, see
+// StreamRow for row arrays. No rows are written as null, as by the grid
+// without rows, Iter of the grid holding empty rows writes the empty
+// array. Action is expected to be assigned in advance. The grid is
+// validated first if debug mode is on, rows are not validated.
+func (g *Grid) StreamJSON(w io.Writer, it RowIterator) error {
+
+	env := *g
+	env.Rows = nil
+	env.RowObjects, env.RowIDs, env.RowUIDs = nil, nil, nil
+	env.RowActions, env.RowLinks, env.RowHighlights = nil, nil, nil
+
+	// the grid without rows keeps column checks.
+	if g.config().Debug {
+		if err := env.Validate(); err != nil {
+			return err
+		}
+	}
+	env.Columns = nil
+
+	// the envelope starts with columns and rows followed by row arrays,
+	// which are omitted being nil.
+	tail, err := json.Marshal(&env)
+	if err != nil {
+		return err
+	}
+	head := []byte(`{"columns":null,"rows":null`)
+	if !bytes.HasPrefix(tail, head) {
+		return errors.New("unexpected grid JSON envelope")
+	}
+	tail = tail[len(head):]
+
+	cols, err := json.Marshal(g.Columns)
+	if err != nil {
+		return err
+	}
+
+	spools := []*spool{
+		{key: "rowObjects", zero: []byte("null")},
+		{key: "rowIds", zero: []byte("0")},
+		{key: "rowUids", zero: []byte(`"` + uuid.Nil.String() + `"`)},
+		{key: "rowActions", zero: []byte("null")},
+		{key: "rowLinks", zero: []byte("null")},
+		{key: "rowHighlights", zero: []byte("null")},
+	}
+	defer func() {
+		for _, s := range spools {
+			s.close()
+		}
+	}()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`{"columns":`)
+	bw.Write(cols)
+	bw.WriteString(`,"rows":`)
+
+	n := 0
+	for it.Next() {
+		r := it.Row()
+
+		buf, err := json.Marshal(r.Cells)
+		if err != nil {
+			return err
+		}
+		sep := byte(',')
+		if n == 0 {
+			sep = '['
+		}
+		bw.WriteByte(sep)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+
+		elems := []struct {
+			v   interface{}
+			set bool
+		}{
+			{r.Object, r.Object != nil || r.Arrays&RowObjectsArray != 0},
+			{r.ID, r.ID != 0 || r.Arrays&RowIDsArray != 0},
+			{r.UID, r.UID != uuid.Nil || r.Arrays&RowUIDsArray != 0},
+			{r.Actions, r.Actions != nil || r.Arrays&RowActionsArray != 0},
+			{r.Links, r.Links != nil || r.Arrays&RowLinksArray != 0},
+			{r.Highlights, r.Highlights != nil || r.Arrays&RowHighlightsArray != 0},
+		}
+		for i, e := range elems {
+			if err := spools[i].add(n, e.v, e.set); err != nil {
+				return err
+			}
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	switch gr, ok := it.(*gridRows); {
+	case n > 0:
+		bw.WriteByte(']')
+	case ok && gr.g.Rows != nil:
+		bw.WriteString("[]")
+	default:
+		bw.WriteString("null")
+	}
+
+	for _, s := range spools {
+		if err := s.copyTo(bw); err != nil {
+			return err
+		}
+	}
+
+	bw.Write(tail)
+	return bw.Flush()
+}
+
+// spool holds the row array written by StreamJSON in the temporary file.
+// The file is created when the first non-zero element is added.
+type spool struct {
+	key  string
+	zero []byte
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// add writes the element of the row. Elements of preceding rows are
+// written as zero values when the file is created.
+func (s *spool) add(row int, v interface{}, set bool) error {
+	if s.f == nil {
+		if !set {
+			return nil
+		}
+
+		f, err := ioutil.TempFile("", "grider-*.json")
+		if err != nil {
+			return err
+		}
+		s.f, s.w = f, bufio.NewWriter(f)
+		for i := 0; i < row; i++ {
+			if i > 0 {
+				s.w.WriteByte(',')
+			}
+			s.w.Write(s.zero)
+		}
+	}
+
+	if row > 0 {
+		s.w.WriteByte(',')
+	}
+	if !set {
+		_, err := s.w.Write(s.zero)
+		return err
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(buf)
+	return err
+}
+
+// copyTo writes the row array to w as the JSON object member.
+func (s *spool) copyTo(w *bufio.Writer) error {
+	if s.f == nil {
+		return nil
+	}
+
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	w.WriteString(`,"` + s.key + `":[`)
+	if _, err := io.Copy(w, s.f); err != nil {
+		return err
+	}
+	return w.WriteByte(']')
+}
+
+func (s *spool) close() {
+	if s.f != nil {
+		s.f.Close()
+		os.Remove(s.f.Name())
+	}
+}
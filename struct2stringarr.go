@@ -1,9 +1,13 @@
 package grider
 
 import (
+	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+
+	"gopkg.in/guregu/null.v3"
 )
 
 // ApplySliceOfStruct converts slice of any struct to Grid,
@@ -21,17 +25,34 @@ func (g *Grid) ApplySliceOfStruct(src interface{}) *Grid {
 		//fmt.Println("s.Len()=0")
 		// if src empty we have to create empty slice element.
 		// and generate values for Columns attribute.
-		g.Columns = extractMeta(g.option.titlePrefix, "", reflect.Zero(t.Elem()), g.option.multiLang)
+		g.Columns = g.extractMeta("", reflect.Zero(t.Elem()))
+		g.checkColumnNames(g.Columns)
+		g.applyOverrides()
+		g.perm = g.orderColumns()
 		return g
 	}
 
+	// keys of the attributes expanded to columns are collected from
+	// all rows.
+	g.expanded = nil
+	g.Columns = g.extractMeta("", s.Index(0))
+	for i := 1; i < s.Len() && len(g.expanded) > 0; i++ {
+		g.Columns = g.extractMeta("", s.Index(i))
+	}
+
 	//fmt.Printf("s.Len()=%d\n", s.Len())
 	for i := 0; i < s.Len(); i++ {
 		row := s.Index(i)
 		if i == 0 {
-			g.Columns = extractMeta(g.option.titlePrefix, "", row, g.option.multiLang)
+			g.checkColumnNames(g.Columns)
+			g.applyOverrides()
+			g.perm = g.orderColumns()
+		}
+		cells, values := g.convertStructValues("", row)
+		g.Rows = append(g.Rows, permute(cells, g.perm))
+		if g.option.sourceValues {
+			g.values = append(g.values, permuteValues(values, g.perm))
 		}
-		g.Rows = append(g.Rows, convertStructValues(row))
 		//	fmt.Printf("dst=%v\n", res.Rows)
 
 		ofunc := row.Addr().MethodByName("Object")
@@ -52,7 +73,9 @@ func (g *Grid) ApplySliceOfStruct(src interface{}) *Grid {
 	return g
 }
 
-func convertStructValues(s reflect.Value) []string {
+// convertStructValues returns formatted cells of the struct s and
+// the source values of the cells.
+func (g *Grid) convertStructValues(parentAttribute string, s reflect.Value) ([]string, []interface{}) {
 	//println("excludeTag", excludeTag)
 	//s := reflect.ValueOf(model).Elem()
 	t := s.Type()
@@ -60,7 +83,11 @@ func convertStructValues(s reflect.Value) []string {
 		panic("convertStructValues's parameter src expected to be a struct")
 	}
 
-	var res []string
+	var (
+		res    []string
+		values []interface{}
+	)
+	cfg := g.config()
 
 	for i := 0; i < s.NumField(); i++ {
 		sf := s.Field(i)
@@ -72,21 +99,38 @@ func convertStructValues(s reflect.Value) []string {
 			//if sf.CanAddr() == false {
 			continue
 		}
-		tag := tf.Tag.Get(FieldTagLabel)
+		tag := tf.Tag.Get(cfg.tagName())
 		//println("fieldName=", tf.Name, "tag", tag)
 		if tag == "-" {
 			continue
 		}
 
+		attr := g.attributeName(tf, tag)
+
+		if isCollection(tf, tag) {
+			cells := g.collectionValues(g.joinAttributeNames(parentAttribute, attr), tag, sf)
+			res = append(res, cells...)
+			for i := range cells {
+				values = append(values, cells[i])
+			}
+			continue
+		}
+
 		if tf.Type.Name() == "" || tf.Anonymous {
+			nested := g.nestedParent(parentAttribute, attr, tf)
 			if tf.Type.Kind() != reflect.Ptr {
-				res = append(res, convertStructValues(sf)...)
+				cells, vals := g.convertStructValues(nested, sf)
+				res = append(res, cells...)
+				values = append(values, vals...)
 			} else {
 				if sf.IsNil() && sf.Kind() == reflect.Struct {
 					sf = reflect.New(tf.Type.Elem())
-					res = append(res, convertStructValues(sf)...)
+					cells, vals := g.convertStructValues(nested, sf)
+					res = append(res, cells...)
+					values = append(values, vals...)
 				} else {
 					res = append(res, "")
+					values = append(values, nil)
 				}
 			}
 			continue
@@ -97,13 +141,15 @@ func convertStructValues(s reflect.Value) []string {
 		//}
 		if tf.Type.Kind() == reflect.Ptr && sf.IsNil() {
 			res = append(res, "")
+			values = append(values, nil)
 			continue
 		}
 
 		//res = append(res, fmt.Sprintf("no json %v", sf.Interface()))
-		res = append(res, formatAttribute(sf, extractTagAttr(tag, "fmt")))
+		res = append(res, cfg.formatAttribute(sf, extractTagAttr(tag, "fmt")))
+		values = append(values, sf.Interface())
 	}
-	return res
+	return res, values
 }
 
 var matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
@@ -153,29 +199,62 @@ func convertTagToJSON(prefix string, tag string) string {
 	return res
 }
 */
-func extractTagAttr(s, substr string) string {
-
-	k := len(substr)
-	if k == 0 {
-		return ""
+// extractTagAttr returns value of the attribute name from the field tag
+// like "type=link,href=/customers/{ID}". It panics if the attribute
+// has no value.
+func extractTagAttr(tag, name string) string {
+	for _, kv := range splitTag(tag) {
+		if kv[0] == name {
+			if kv[1] == "" {
+				panic("struct field tag has attr '" + name + "' without value")
+			}
+			return kv[1]
+		}
 	}
-	substr += "="
-	k++
+	return ""
+}
 
-	pos := strings.Index(s, substr)
-	if pos < 0 {
-		return ""
-	}
+// splitTag splits the field tag to trimmed attribute names and values.
+// Values in single quotes may hold commas and spaces, as instance
+// "expand=join,sep=', '". Attributes without "=" have empty names.
+func splitTag(tag string) [][2]string {
+	var (
+		res    [][2]string
+		quoted bool
+		from   int
+	)
+	for i := 0; i <= len(tag); i++ {
+		if i < len(tag) {
+			if tag[i] == '\'' {
+				quoted = !quoted
+			}
+			if quoted || tag[i] != ',' {
+				continue
+			}
+		}
 
-	qpos := strings.Index(s[pos+k:], ",")
-	if qpos < 0 {
-		if len(s[pos+k:]) > 1 {
-			return s[pos+k : len(s)-1]
+		var kv [2]string
+		if k := strings.SplitN(tag[from:i], "=", 2); len(k) == 2 {
+			kv[0] = strings.TrimSpace(k[0])
+			kv[1] = strings.TrimSpace(k[1])
+			if n := len(kv[1]); n >= 2 && kv[1][0] == '\'' && kv[1][n-1] == '\'' {
+				kv[1] = kv[1][1 : n-1]
+			}
 		}
-		panic("struct field tag has attr 'fmt' without value")
+		res = append(res, kv)
+		from = i + 1
 	}
+	return res
+}
 
-	return s[pos+k : pos+k+qpos]
+// tagInt returns the integer value of the field tag attribute name.
+// It panics if the value isn't an integer.
+func tagInt(name, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		panic("struct field tag attr '" + name + "' expects integer, got " + value)
+	}
+	return n
 }
 
 func addPrefixToColumn(s, substr string) string {
@@ -187,7 +266,7 @@ func addPrefixToColumn(s, substr string) string {
 	return s
 }
 
-func extractMeta(titlePrefix string, parentAttribute string, s reflect.Value, multiLang bool) []Column {
+func (g *Grid) extractMeta(parentAttribute string, s reflect.Value) []Column {
 
 	var res []Column
 
@@ -208,30 +287,35 @@ func extractMeta(titlePrefix string, parentAttribute string, s reflect.Value, mu
 			//if sf.CanAddr() == false {
 			continue
 		}
-		tag := tf.Tag.Get(FieldTagLabel)
+		tag := tf.Tag.Get(g.config().tagName())
 		if tag == "-" {
 			continue
 		}
 		//	println(tf.Name, tf.Tag, tf.Anonymous, tf.Type.Name(), "; tag=", tag)
 
-		//snakeName := ToSnakeCase(tf.Name)
-		snakeName := tf.Name
+		snakeName := g.attributeName(tf, tag)
+
+		if isCollection(tf, tag) {
+			res = append(res, g.collectionColumns(parentAttribute, snakeName, tag, sf)...)
+			continue
+		}
 
 		if tf.Type.Name() == "" || tf.Anonymous {
 			//fmt.Println("struct with no type")
 			var gc []Column
+			nested := g.nestedParent(parentAttribute, snakeName, tf)
 			if tf.Type.Kind() != reflect.Ptr {
-				gc = extractMeta(titlePrefix, snakeName, sf, multiLang)
+				gc = g.extractMeta(nested, sf)
 			} else {
 				if sf.IsNil() {
 					if tf.Type.Kind() == reflect.Struct {
 						mock := reflect.New(tf.Type.Elem())
-						gc = extractMeta(titlePrefix, snakeName, mock, multiLang)
+						gc = g.extractMeta(nested, mock)
 					} else {
-						res = append(res, convertTagToGridColumn(titlePrefix, parentAttribute, snakeName, tag, multiLang))
+						res = append(res, g.convertTagToGridColumn(parentAttribute, snakeName, tag, tf.Type))
 					}
 				} else {
-					gc = extractMeta(titlePrefix, snakeName, sf, multiLang)
+					gc = g.extractMeta(nested, sf)
 				}
 			}
 			//fmt.Printf("anonym: %v\n", h)
@@ -241,41 +325,78 @@ func extractMeta(titlePrefix string, parentAttribute string, s reflect.Value, mu
 			}
 		} else {
 			//	println("bala", sf.String())
-			res = append(res, convertTagToGridColumn(titlePrefix, parentAttribute, snakeName, tag, multiLang))
+			res = append(res, g.convertTagToGridColumn(parentAttribute, snakeName, tag, tf.Type))
 			continue
 		}
 
 	}
 	return res
 }
-func joinAttributeNames(parentAttribute, attribute string) string {
-	if parentAttribute == "" {
-		return attribute
+
+var (
+	nullTimeType  = reflect.TypeOf(null.Time{})
+	nullIntType   = reflect.TypeOf(null.Int{})
+	nullFloatType = reflect.TypeOf(null.Float{})
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// attributeType returns the column type of the attribute type t as
+// ApplySQLRows does for database types: "date" for time.Time and
+// null.Time, "number" for numbers, null.Int and null.Float. Types having
+// the config formatter or String method, as enums, have no type.
+func (g *Grid) attributeType(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	if _, ok := g.config().Formatters[t.String()]; ok {
+		return ""
 	}
-	return parentAttribute + attribute
-}
 
-func convertTagToGridColumn(titlePrefix string, parentAttribute, attribute string, tag string, multiLang bool) Column {
+	switch t {
+	case timeType, nullTimeType:
+		return "date"
+	case nullIntType, nullFloatType:
+		return "number"
+	}
 
-	var res = Column{Name: joinAttributeNames(parentAttribute, attribute)}
-	if multiLang {
-		res.Title = "%" + titlePrefix + res.Name + "%"
-	} else {
-		res.Title = titlePrefix + res.Name
+	if t.Implements(stringerType) || reflect.PtrTo(t).Implements(stringerType) {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
 	}
+	return ""
+}
+
+// columnTitle returns default title of the column. The title is
+// a resource code like "%prefixName%" if i18n option is set.
+func (g *Grid) columnTitle(name string) string {
+	if g.option.multiLang {
+		return "%" + g.option.titlePrefix + name + "%"
+	}
+	return g.option.titlePrefix + name
+}
+
+// convertTagToGridColumn returns the column of the attribute of type t
+// described by the tag. The type "number" or "date" is inferred from t
+// if the tag doesn't set it, see attributeType.
+func (g *Grid) convertTagToGridColumn(parentAttribute, attribute string, tag string, t reflect.Type) Column {
+
+	var res = Column{Name: g.joinAttributeNames(parentAttribute, attribute)}
+	res.Title = g.columnTitle(res.Name)
+	res.Type = g.attributeType(t)
 
 	if tag == "" {
 		return res
 	}
 
-	tags := strings.Split(tag, ",")
-	for i := range tags {
-		k := strings.Split(tags[i], "=")
-		if len(k) < 2 {
+	for _, k := range splitTag(tag) {
+		if k[0] == "" {
 			panic("wrong tag" + tag)
 		}
-		k[0] = strings.TrimSpace(k[0])
-		k[1] = strings.TrimSpace(k[1])
 
 		switch k[0] {
 		case "type":
@@ -302,8 +423,27 @@ func convertTagToGridColumn(titlePrefix string, parentAttribute, attribute strin
 			res.IconsAlign = k[1]
 		case "target":
 			res.Target = k[1]
+		case "title":
+			res.Title = k[1]
+		case "order":
+			res.Order = tagInt(k[0], k[1])
+		case "width":
+			res.Width = tagInt(k[0], k[1])
+		case "minWidth":
+			res.MinWidth = tagInt(k[0], k[1])
+		case "pin":
+			res.Pin = k[1]
+		case "group":
+			res.Group = k[1]
+		case "search":
+			res.NoSearch = (k[1] == "false")
+		case "sort":
+			res.Sort = k[1]
+		default:
+			res.setFilterTag(k[0], k[1])
 		}
 	}
+	res.completeFilter()
 
 	//	fmt.Printf("res=%#v\n", res)
 
@@ -0,0 +1,283 @@
+// Code generated by grider. DO NOT EDIT.
+
+export interface Page {
+  id?: number;
+  header?: Header;
+  widgets?: Widget[];
+  tabs?: Tab[];
+  action?: ActionSet;
+  pageActions?: ActionCode[];
+}
+
+export interface Header {
+  id?: number;
+  leftIcons?: Icon[];
+  title?: string;
+  subTitle?: string;
+  rightIcons?: Icon[];
+  url?: string;
+  bgColor?: string;
+}
+
+export interface Icon {
+  name: string;
+  color?: string;
+}
+
+export interface Tab {
+  header?: Header;
+  tabActions?: ActionCode[];
+  widgets?: Widget[];
+  isActive?: boolean;
+  isInitRequired?: boolean;
+  isDisabled?: boolean;
+}
+
+export type ActionCode = string;
+
+export type ActionSet = Record<string, Action>;
+
+export interface Action {
+  code?: ActionCode;
+  perm?: string;
+  title?: string;
+  icon?: Icon;
+  directCall?: DirectCall;
+}
+
+export interface DirectCall {
+  isConfirmationRequired?: boolean;
+  confirmationMessage?: string;
+  method?: string;
+  path?: string;
+  body?: unknown;
+}
+
+export interface Grid {
+  columns: Column[] | null;
+  rows: string[][] | null;
+  rowObjects?: unknown[];
+  rowIds?: number[];
+  rowUids?: string[];
+  rowActions?: ActionCode[][];
+  rowLinks?: Link[][];
+  rowHighlights?: Highlight[][];
+  headerGroups?: HeaderCell[][];
+  gridActions?: ActionCode[];
+  action?: ActionSet;
+  isDownloadable: boolean;
+  isFilterable: boolean;
+  noPagination?: boolean;
+  paginationType: PaginationType;
+  paging?: Paging;
+  pageInfo?: PageInfo;
+  views?: ViewInfo[];
+  activeView?: string;
+  facets?: Facet[];
+}
+
+export interface Column {
+  name: string;
+  hidden?: boolean;
+  sortable?: boolean;
+  filterable?: boolean;
+  title?: string;
+  perm?: string;
+  type?: string;
+  href?: string;
+  align?: string;
+  caption?: string;
+  method?: string;
+  icons?: string;
+  ialign?: string;
+  target?: string;
+  order?: number;
+  width?: number;
+  minWidth?: number;
+  pin?: string;
+  group?: string;
+  noSearch?: boolean;
+  sort?: string;
+  filter?: FilterSpec;
+}
+
+export interface FilterSpec {
+  type: FilterType;
+  ops: FilterOp[] | null;
+  options?: FilterOption[];
+  dict?: string;
+  presets?: string[];
+}
+
+export type FilterType = "text" | "number" | "date" | "enum" | "bool";
+
+export type FilterOp = "eq" | "ne" | "contains" | "between" | "in" | "isnull";
+
+export interface FilterOption {
+  value: string;
+  title?: string;
+}
+
+export interface Link {
+  text: string;
+  url: string;
+  target?: string;
+}
+
+export interface Highlight {
+  column: number;
+  ranges: number[][] | null;
+}
+
+export interface HeaderCell {
+  title: string;
+  start: number;
+  span: number;
+}
+
+export type PaginationType = "server" | "client" | "without" | "cursor";
+
+export interface Paging {
+  page: number;
+  pageSize: number;
+  totalRows: number;
+  totalPages: number;
+  pageSizes?: number[];
+}
+
+export interface PageInfo {
+  hasNext: boolean;
+  hasPrev: boolean;
+  total?: number;
+  nextCursor?: string;
+  prevCursor?: string;
+}
+
+export interface ViewInfo {
+  id: string;
+  name: string;
+}
+
+export interface Facet {
+  column: string;
+  values?: FacetValue[];
+  min?: string;
+  max?: string;
+  nulls: number;
+}
+
+export interface FacetValue {
+  value: string;
+  title?: string;
+  count: number;
+}
+
+export type WidgetType = "attrval" | "media" | "grid" | "map" | "chart" | "custom" | "lazy" | "content" | "empty";
+
+export interface Line {
+  id?: number;
+  icon?: Icon;
+  label?: string;
+  value?: string;
+  type?: LineType;
+  refBook?: RefBookType;
+  suggestion?: SuggestionType;
+  url?: string;
+  actions?: ActionCode[];
+}
+
+export type LineType = "" | "href" | "exthref" | "refbook" | "suggestion";
+
+export interface RefBookType {
+  name: string;
+  selectedId: number;
+  sumbitUrl: string;
+}
+
+export interface SuggestionType {
+  name: string;
+  selectedId?: number;
+  uid?: string;
+  submitUrl: string;
+}
+
+export interface Media {
+  thumbnailUrl: string;
+  url: string;
+  isVideo?: boolean;
+}
+
+export type ContentBodyType = "text" | "html" | "markdown";
+
+export interface AttrValueWidget {
+  id?: number;
+  type: "attrval";
+  header?: Header;
+  width?: number;
+  widgetActions?: ActionCode[];
+  action?: ActionSet;
+  object?: unknown;
+  lines?: Line[];
+}
+
+export interface MediaWidget {
+  id?: number;
+  type: "media";
+  header?: Header;
+  width?: number;
+  widgetActions?: ActionCode[];
+  action?: ActionSet;
+  object?: unknown;
+  media?: Media[];
+}
+
+export interface GridWidget {
+  id?: number;
+  type: "grid";
+  header?: Header;
+  width?: number;
+  widgetActions?: ActionCode[];
+  action?: ActionSet;
+  object?: unknown;
+  grid?: Grid;
+}
+
+export interface ContentWidget {
+  id?: number;
+  type: "text" | "html" | "markdown";
+  header?: Header;
+  width?: number;
+  widgetActions?: ActionCode[];
+  action?: ActionSet;
+  object?: unknown;
+  body: string;
+}
+
+export interface LazyWidget {
+  id?: number;
+  type: "lazy";
+  header?: Header;
+  width?: number;
+  widgetActions?: ActionCode[];
+  action?: ActionSet;
+  object?: unknown;
+  url: string;
+}
+
+export interface EmptyWidget {
+  id?: number;
+  type: "empty";
+  header?: Header;
+  width?: number;
+  widgetActions?: ActionCode[];
+  action?: ActionSet;
+  object?: unknown;
+}
+
+export type Widget =
+  | AttrValueWidget
+  | MediaWidget
+  | GridWidget
+  | ContentWidget
+  | LazyWidget
+  | EmptyWidget;
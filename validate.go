@@ -0,0 +1,290 @@
+package grider
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes a single problem found by Validate.
+type ValidationError struct {
+	// Path locates the problem, as instance "tabs[1].widgets[0].grid.rows[5]".
+	Path string
+
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+// ValidationErrors holds all problems found by Validate.
+type ValidationErrors []ValidationError
+
+func (ve ValidationErrors) Error() string {
+	s := make([]string, len(ve))
+	for i := range ve {
+		s[i] = ve[i].Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (ve *ValidationErrors) add(path, format string, a ...interface{}) {
+	*ve = append(*ve, ValidationError{Path: path, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (ve ValidationErrors) err() error {
+	if len(ve) == 0 {
+		return nil
+	}
+	return ve
+}
+
+// SetDebugMode turns on validation of grids and pages before
+// JSON serialization. If validation fails JSON returns ValidationErrors.
+// It sets Debug of the default config.
+func SetDebugMode(b bool) {
+	updateDefaultConfig(func(c *Config) { c.Debug = b })
+}
+
+// Validate checks consistency of the grid. It returns ValidationErrors
+// holding all found problems or nil.
+func (g *Grid) Validate() error {
+	var ve ValidationErrors
+	g.validate("", &ve)
+	return ve.err()
+}
+
+func (g *Grid) validate(path string, ve *ValidationErrors) {
+
+	names := make(map[string]int, len(g.Columns))
+	for i := range g.Columns {
+		cp := index(join(path, "columns"), i)
+		if g.Columns[i].Name == "" {
+			ve.add(cp, "column name is empty")
+			continue
+		}
+		if j, ok := names[g.Columns[i].Name]; ok {
+			ve.add(cp, "duplicate column name %q, see columns[%d]", g.Columns[i].Name, j)
+			continue
+		}
+		names[g.Columns[i].Name] = i
+	}
+
+	for i := range g.Columns {
+		switch g.Columns[i].Pin {
+		case "", "left", "right":
+		default:
+			ve.add(index(join(path, "columns"), i), "pin %q expected to be \"left\" or \"right\"", g.Columns[i].Pin)
+		}
+		if g.Columns[i].Filter != nil {
+			validateFilterSpec(join(index(join(path, "columns"), i), "filter"), g.Columns[i].Filter, ve)
+		}
+	}
+
+	for i := range g.Columns {
+		if g.Columns[i].Type != "link" || g.Columns[i].Href == "" {
+			continue
+		}
+		if _, err := ParseLinkTemplate(g.Columns[i].Href, g.Columns); err != nil {
+			ve.add(index(join(path, "columns"), i), "%s", err.Error())
+		}
+	}
+
+	for i := range g.Rows {
+		if len(g.Rows[i]) != len(g.Columns) {
+			ve.add(index(join(path, "rows"), i), "row has %d cells, expected %d", len(g.Rows[i]), len(g.Columns))
+		}
+	}
+
+	rowAttrs := []struct {
+		name string
+		n    int
+	}{
+		{"rowObjects", len(g.RowObjects)},
+		{"rowIds", len(g.RowIDs)},
+		{"rowUids", len(g.RowUIDs)},
+		{"rowActions", len(g.RowActions)},
+		{"rowLinks", len(g.RowLinks)},
+		{"rowHighlights", len(g.RowHighlights)},
+	}
+	for _, ra := range rowAttrs {
+		if ra.n != 0 && ra.n != len(g.Rows) {
+			ve.add(join(path, ra.name), "has %d elements, expected %d (rows)", ra.n, len(g.Rows))
+		}
+	}
+
+	if g.Paging != nil && g.PaginationType != PaginationServer {
+		ve.add(join(path, "paging"), "is set for pagination type %q", g.PaginationType.String())
+	}
+
+	if g.Action != nil {
+		checkActionValues(join(path, "action"), g.Action, ve)
+		g.Walk(Visitor{ActionCode: checkActionCode(path, g.Action, ve)})
+	}
+}
+
+// ValidateActions checks that every action code used by the grid is
+// found in as, the set of actions supported by the application, and
+// that Action holds assigned values. It returns ValidationErrors
+// holding all found problems or nil.
+func (g *Grid) ValidateActions(as ActionSet) error {
+	var ve ValidationErrors
+	if g.Action != nil {
+		checkActionValues("action", g.Action, &ve)
+	}
+	g.Walk(Visitor{ActionCode: checkActionCode("", as, &ve)})
+	return ve.err()
+}
+
+// Validate checks consistency of the page, its tabs, widgets and grids.
+// It returns ValidationErrors holding all found problems or nil.
+func (p *Page) Validate() error {
+	var ve ValidationErrors
+
+	active := -1
+	for i := range p.Tabs {
+		if !p.Tabs[i].IsActive {
+			continue
+		}
+		if active != -1 {
+			ve.add(index("tabs", i), "more than one active tab, see tabs[%d]", active)
+			continue
+		}
+		active = i
+	}
+
+	ids := make(map[int]string)
+	v := Visitor{
+		Widget: func(path string, w Widgeter) error {
+			validateWidget(path, w, &ve)
+			if b := BaseWidget(w); b != nil && b.ID != 0 {
+				if prev, ok := ids[b.ID]; ok {
+					ve.add(path, "duplicate widget id %d, see %s", b.ID, prev)
+				} else {
+					ids[b.ID] = path
+				}
+			}
+			return nil
+		},
+		Grid: func(path string, g *Grid) error {
+			g.validate(path, &ve)
+			return nil
+		},
+	}
+
+	if p.Action != nil {
+		checkActionValues("action", p.Action, &ve)
+		v.ActionCode = checkActionCode("", p.Action, &ve)
+	}
+
+	p.Walk(v)
+	return ve.err()
+}
+
+// ValidateActions checks that every action code used by the page, its
+// tabs and grids is found in as, the set of actions supported by the
+// application, and that Action of the page and of the grids holds
+// assigned values. It returns ValidationErrors holding all found
+// problems or nil.
+func (p *Page) ValidateActions(as ActionSet) error {
+	var ve ValidationErrors
+	if p.Action != nil {
+		checkActionValues("action", p.Action, &ve)
+	}
+	p.Walk(Visitor{
+		Grid: func(path string, g *Grid) error {
+			if g.Action != nil {
+				checkActionValues(join(path, "action"), g.Action, &ve)
+			}
+			return nil
+		},
+		ActionCode: checkActionCode("", as, &ve),
+	})
+	return ve.err()
+}
+
+// Validate checks consistency of the tab, its widgets and grids.
+// It returns ValidationErrors holding all found problems or nil.
+func (t *Tab) Validate() error {
+	p := Page{Tabs: []Tab{*t}}
+	err := p.Validate()
+	if ve, ok := err.(ValidationErrors); ok {
+		for i := range ve {
+			ve[i].Path = strings.TrimPrefix(strings.TrimPrefix(ve[i].Path, "tabs[0]"), ".")
+		}
+	}
+	return err
+}
+
+// ValidateWidget checks consistency of the widget w and its grid.
+// It returns ValidationErrors holding all found problems or nil.
+//
+// Application defined widgets can implement method Validate() error
+// to be validated by ValidateWidget, Page.Validate and Tab.Validate.
+func ValidateWidget(w Widgeter) error {
+	var ve ValidationErrors
+	WalkWidget(w, Visitor{
+		Widget: func(path string, w Widgeter) error {
+			validateWidget(path, w, &ve)
+			return nil
+		},
+		Grid: func(path string, g *Grid) error {
+			g.validate(path, &ve)
+			return nil
+		},
+	})
+	return ve.err()
+}
+
+func validateWidget(path string, w Widgeter, ve *ValidationErrors) {
+
+	if b := BaseWidget(w); b != nil && b.Type != 0 && b.Type != w.WidgetType() {
+		ve.add(path, "widget type %q differs from %q", b.Type, w.WidgetType())
+	}
+
+	switch w := w.(type) {
+	case GridWidget:
+		if w.Grid == nil {
+			ve.add(path, "grid widget has no grid")
+		}
+	case *GridWidget:
+		if w.Grid == nil {
+			ve.add(path, "grid widget has no grid")
+		}
+	case interface{ Validate() error }:
+		if err := w.Validate(); err != nil {
+			ve.add(path, "%s", err.Error())
+		}
+	}
+}
+
+// checkActionValues adds errors for actions of as having zero values,
+// that is codes added by ActionSet.Add and never assigned.
+func checkActionValues(path string, as ActionSet, ve *ValidationErrors) {
+	codes := make([]string, 0, len(as))
+	for code := range as {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if as[ActionCode(code)] == (Action{}) {
+			ve.add(path, "value of action %s is not assigned", strconv.Quote(code))
+		}
+	}
+}
+
+func checkActionCode(prefix string, as ActionSet, ve *ValidationErrors) func(string, ActionCode) error {
+	return func(path string, code ActionCode) error {
+		if _, ok := as[code]; !ok {
+			ve.add(join(prefix, path), "unsupported action code %s", strconv.Quote(string(code)))
+		}
+		return nil
+	}
+}
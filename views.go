@@ -0,0 +1,420 @@
+package grider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrViewNotFound is returned by ViewStore if the view doesn't exist.
+var ErrViewNotFound = errors.New("grid view not found")
+
+// ViewColumn describes the column presentation saved in the view.
+// Nil attributes keep the column attributes.
+type ViewColumn struct {
+	Name   string `json:"name"`
+	Width  *int   `json:"width,omitempty"`
+	Hidden *bool  `json:"hidden,omitempty"`
+}
+
+// View is a named set of column order, widths, hidden columns, sort
+// and filters saved by the user for the grid. Grid is the grid name
+// set by WithName.
+type View struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Grid    string       `json:"grid"`
+	Owner   string       `json:"owner"`
+	Columns []ViewColumn `json:"columns,omitempty"`
+	Sort    []SortKey    `json:"sort,omitempty"`
+	Filters []Filter     `json:"filters,omitempty"`
+}
+
+// ViewInfo describes the view available for the grid in the grid JSON.
+type ViewInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ViewStore keeps views of the grids by the owner.
+type ViewStore interface {
+	// List returns views of the grid saved by the owner ordered by name.
+	List(ctx context.Context, grid, owner string) ([]View, error)
+
+	// Get returns the view or ErrViewNotFound.
+	Get(ctx context.Context, grid, owner, id string) (View, error)
+
+	// Save adds or replaces the view. A new ID is assigned to
+	// the view without ID.
+	Save(ctx context.Context, v *View) error
+
+	// Delete removes the view. Deleting missing view is not an error.
+	Delete(ctx context.Context, grid, owner, id string) error
+}
+
+// MemoryViewStore is ViewStore keeping views in memory.
+type MemoryViewStore struct {
+	mu    sync.RWMutex
+	views map[string]map[string]View // by grid and owner, by id.
+}
+
+// NewMemoryViewStore returns an empty MemoryViewStore.
+func NewMemoryViewStore() *MemoryViewStore {
+	return &MemoryViewStore{views: make(map[string]map[string]View)}
+}
+
+func viewKey(grid, owner string) string {
+	return url.PathEscape(grid) + "/" + url.PathEscape(owner)
+}
+
+func (s *MemoryViewStore) List(ctx context.Context, grid, owner string) ([]View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := s.views[viewKey(grid, owner)]
+	res := make([]View, 0, len(m))
+	for _, v := range m {
+		res = append(res, v)
+	}
+	sortViews(res)
+	return res, nil
+}
+
+func (s *MemoryViewStore) Get(ctx context.Context, grid, owner, id string) (View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.views[viewKey(grid, owner)][id]
+	if !ok {
+		return View{}, ErrViewNotFound
+	}
+	return v, nil
+}
+
+func (s *MemoryViewStore) Save(ctx context.Context, v *View) error {
+	if v.ID == "" {
+		v.ID = uuid.New().String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := viewKey(v.Grid, v.Owner)
+	if s.views[k] == nil {
+		s.views[k] = make(map[string]View)
+	}
+	s.views[k][v.ID] = *v
+	return nil
+}
+
+func (s *MemoryViewStore) Delete(ctx context.Context, grid, owner, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.views[viewKey(grid, owner)], id)
+	return nil
+}
+
+// FileViewStore is ViewStore keeping views of the grid and the owner
+// in the JSON file Dir/grid/owner.json. Grid and owner names holding
+// path separators or ".." are rejected.
+type FileViewStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewFileViewStore returns FileViewStore keeping files in dir.
+func NewFileViewStore(dir string) *FileViewStore {
+	return &FileViewStore{Dir: dir}
+}
+
+func (s *FileViewStore) fileName(grid, owner string) (string, error) {
+	for _, name := range []string{grid, owner} {
+		if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+			return "", errors.New("invalid grid view store name " + strconv.Quote(name))
+		}
+	}
+	return filepath.Join(s.Dir, url.PathEscape(grid), url.PathEscape(owner)+".json"), nil
+}
+
+// read returns views of the file or nil if the file doesn't exist.
+func (s *FileViewStore) read(grid, owner string) ([]View, error) {
+	fn, err := s.fileName(grid, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res []View
+	if err := json.Unmarshal(buf, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// write replaces the file atomically.
+func (s *FileViewStore) write(grid, owner string, views []View) error {
+	fn, err := s.fileName(grid, owner)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+		return err
+	}
+
+	buf, err := json.MarshalIndent(views, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), ".views-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fn)
+}
+
+func (s *FileViewStore) List(ctx context.Context, grid, owner string) ([]View, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.read(grid, owner)
+	if err != nil {
+		return nil, err
+	}
+	sortViews(res)
+	return res, nil
+}
+
+func (s *FileViewStore) Get(ctx context.Context, grid, owner, id string) (View, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	views, err := s.read(grid, owner)
+	if err != nil {
+		return View{}, err
+	}
+	for i := range views {
+		if views[i].ID == id {
+			return views[i], nil
+		}
+	}
+	return View{}, ErrViewNotFound
+}
+
+func (s *FileViewStore) Save(ctx context.Context, v *View) error {
+	if v.ID == "" {
+		v.ID = uuid.New().String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	views, err := s.read(v.Grid, v.Owner)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range views {
+		if views[i].ID == v.ID {
+			views[i] = *v
+			found = true
+		}
+	}
+	if !found {
+		views = append(views, *v)
+	}
+	return s.write(v.Grid, v.Owner, views)
+}
+
+func (s *FileViewStore) Delete(ctx context.Context, grid, owner, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	views, err := s.read(grid, owner)
+	if err != nil {
+		return err
+	}
+
+	res := views[:0]
+	for i := range views {
+		if views[i].ID != id {
+			res = append(res, views[i])
+		}
+	}
+	if len(res) == len(views) {
+		return nil
+	}
+	return s.write(grid, owner, res)
+}
+
+func sortViews(views []View) {
+	sort.SliceStable(views, func(i, j int) bool {
+		if views[i].Name != views[j].Name {
+			return views[i].Name < views[j].Name
+		}
+		return views[i].ID < views[j].ID
+	})
+}
+
+// ApplyView applies the view to the grid: columns listed in the view
+// go first in the view order and take width and hidden set by the view,
+// other columns follow in their current order. Then rows are filtered
+// and sorted. Cells and row attributes follow columns and rows.
+// The grid isn't changed if filters or sort keys of the view are wrong.
+func (g *Grid) ApplyView(v View) error {
+
+	if _, err := g.filterColumns(v.Filters); err != nil {
+		return err
+	}
+	for _, k := range v.Sort {
+		if _, err := g.newSortComparer(k); err != nil {
+			return err
+		}
+	}
+
+	idx := make([]int, 0, len(g.Columns))
+	used := make(map[int]bool, len(g.Columns))
+	for _, vc := range v.Columns {
+		if i := g.ColumnIndex(vc.Name); i != -1 && !used[i] {
+			idx = append(idx, i)
+			used[i] = true
+		}
+	}
+	for i := range g.Columns {
+		if !used[i] {
+			idx = append(idx, i)
+		}
+	}
+	g.selectColumns(idx)
+
+	for _, vc := range v.Columns {
+		if i := g.ColumnIndex(vc.Name); i != -1 {
+			setInt(&g.Columns[i].Width, vc.Width)
+			setBool(&g.Columns[i].Hidden, vc.Hidden)
+		}
+	}
+	g.BuildHeaderGroups()
+
+	if err := g.FilterRows(v.Filters...); err != nil {
+		return err
+	}
+	if err := g.SortRows(v.Sort...); err != nil {
+		return err
+	}
+
+	g.ActiveView = v.ID
+	return nil
+}
+
+// SetViews lists views in the grid JSON.
+func (g *Grid) SetViews(views []View) {
+	g.Views = make([]ViewInfo, len(views))
+	for i := range views {
+		g.Views[i] = ViewInfo{ID: views[i].ID, Name: views[i].Name}
+	}
+}
+
+// LoadViews reads views of the grid saved by the owner, migrates them
+// to the grid columns and lists them in the grid JSON. Migrated views
+// are saved back to the store. See MigrateView.
+func (g *Grid) LoadViews(ctx context.Context, s ViewStore, owner string, renames map[string]string) ([]View, error) {
+
+	views, err := s.List(ctx, g.option.name, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range views {
+		if !MigrateView(&views[i], g.Columns, renames) {
+			continue
+		}
+		if err := s.Save(ctx, &views[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	g.SetViews(views)
+	return views, nil
+}
+
+// MigrateView updates the view after columns of the grid are changed.
+// References to the columns renamed in renames (old name to new name)
+// are replaced, references to the columns missing in cols are removed.
+// It returns true if the view is changed.
+func MigrateView(v *View, cols []Column, renames map[string]string) bool {
+
+	known := make(map[string]bool, len(cols))
+	for i := range cols {
+		known[cols[i].Name] = true
+	}
+
+	changed := false
+	migrate := func(name string) (string, bool) {
+		if n, ok := renames[name]; ok && n != name {
+			changed = true
+			name = n
+		}
+		if !known[name] {
+			changed = true
+			return "", false
+		}
+		return name, true
+	}
+
+	vcs := make([]ViewColumn, 0, len(v.Columns))
+	for _, vc := range v.Columns {
+		if n, ok := migrate(vc.Name); ok {
+			vc.Name = n
+			vcs = append(vcs, vc)
+		}
+	}
+
+	sks := make([]SortKey, 0, len(v.Sort))
+	for _, sk := range v.Sort {
+		if n, ok := migrate(sk.Column); ok {
+			sk.Column = n
+			sks = append(sks, sk)
+		}
+	}
+
+	fs := make([]Filter, 0, len(v.Filters))
+	for _, f := range v.Filters {
+		if n, ok := migrate(f.Column); ok {
+			f.Column = n
+			fs = append(fs, f)
+		}
+	}
+
+	if changed {
+		v.Columns, v.Sort, v.Filters = vcs, sks, fs
+	}
+	return changed
+}
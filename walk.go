@@ -0,0 +1,277 @@
+package grider
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Visitor holds callbacks invoked by Walk for every node of the Page tree.
+// A nil callback is skipped. The path argument locates the node inside
+// the page, as instance "tabs[1].widgets[0].grid.columns[2]".
+//
+// Walking stops at the first error returned by a callback.
+type Visitor struct {
+	Header     func(path string, h *Header) error
+	Tab        func(path string, t *Tab) error
+	Widget     func(path string, w Widgeter) error
+	Line       func(path string, l *Line) error
+	Grid       func(path string, g *Grid) error
+	Column     func(path string, c *Column) error
+	ActionCode func(path string, code ActionCode) error
+}
+
+// Walk visits page header, page actions, page widgets and tabs
+// in the order they are serialized to JSON.
+func (p *Page) Walk(v Visitor) error {
+	if err := v.header("header", p.Header); err != nil {
+		return err
+	}
+
+	if err := v.actionCodes("pageActions", p.PageActions); err != nil {
+		return err
+	}
+
+	if err := v.widgets("widgets", p.Widgets); err != nil {
+		return err
+	}
+
+	for i := range p.Tabs {
+		if err := p.Tabs[i].walk(index("tabs", i), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walk visits tab header, tab actions and tab widgets.
+func (t *Tab) Walk(v Visitor) error {
+	return t.walk("", v)
+}
+
+func (t *Tab) walk(path string, v Visitor) error {
+	if v.Tab != nil {
+		if err := v.Tab(path, t); err != nil {
+			return err
+		}
+	}
+
+	if err := v.header(join(path, "header"), t.Header); err != nil {
+		return err
+	}
+
+	if err := v.actionCodes(join(path, "tabActions"), t.TabActions); err != nil {
+		return err
+	}
+
+	return v.widgets(join(path, "widgets"), t.Widgets)
+}
+
+// WalkWidget visits the widget and its content. Widgets can be passed
+// as values (GridWidget{}) or as pointers (&GridWidget{}).
+func WalkWidget(w Widgeter, v Visitor) error {
+	return v.widget("", w)
+}
+
+// Walk visits the grid, its columns, grid actions and row actions.
+func (g *Grid) Walk(v Visitor) error {
+	return v.grid("", g)
+}
+
+func (v Visitor) widgets(path string, ws []Widgeter) error {
+	for i := range ws {
+		if err := v.widget(index(path, i), ws[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v Visitor) widget(path string, w Widgeter) error {
+	if w == nil {
+		return nil
+	}
+
+	if v.Widget != nil {
+		if err := v.Widget(path, w); err != nil {
+			return err
+		}
+	}
+
+	if b := BaseWidget(w); b != nil {
+		if err := v.header(join(path, "header"), b.Header); err != nil {
+			return err
+		}
+		if err := v.actionCodes(join(path, "widgetActions"), b.Actions); err != nil {
+			return err
+		}
+	}
+
+	switch w := w.(type) {
+	case AttrValueWidget:
+		return v.lines(join(path, "lines"), w.Lines)
+	case *AttrValueWidget:
+		return v.lines(join(path, "lines"), w.Lines)
+	case GridWidget:
+		return v.grid(join(path, "grid"), w.Grid)
+	case *GridWidget:
+		return v.grid(join(path, "grid"), w.Grid)
+	}
+	return nil
+}
+
+func (v Visitor) lines(path string, ls []Line) error {
+	for i := range ls {
+		lp := index(path, i)
+		if v.Line != nil {
+			if err := v.Line(lp, &ls[i]); err != nil {
+				return err
+			}
+		}
+		if err := v.actionCodes(join(lp, "actions"), ls[i].Actions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v Visitor) grid(path string, g *Grid) error {
+	if g == nil {
+		return nil
+	}
+
+	if v.Grid != nil {
+		if err := v.Grid(path, g); err != nil {
+			return err
+		}
+	}
+
+	if v.Column != nil {
+		for i := range g.Columns {
+			if err := v.Column(index(join(path, "columns"), i), &g.Columns[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := v.actionCodes(join(path, "gridActions"), g.GridActions); err != nil {
+		return err
+	}
+
+	for i := range g.RowActions {
+		if err := v.actionCodes(index(join(path, "rowActions"), i), g.RowActions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v Visitor) header(path string, h *Header) error {
+	if h == nil || v.Header == nil {
+		return nil
+	}
+	return v.Header(path, h)
+}
+
+func (v Visitor) actionCodes(path string, codes []ActionCode) error {
+	if v.ActionCode == nil {
+		return nil
+	}
+	for i := range codes {
+		if err := v.ActionCode(index(path, i), codes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var widgetPtrType = reflect.TypeOf((*Widget)(nil))
+
+// BaseWidget returns embedded *Widget of the widget w. It returns nil
+// if w does not embed *Widget or the embedded pointer is nil.
+//
+// Application defined widgets are supported if they embed *Widget
+// like the widgets of the package do.
+func BaseWidget(w Widgeter) *Widget {
+	switch w := w.(type) {
+	case AttrValueWidget:
+		return w.Widget
+	case *AttrValueWidget:
+		return w.Widget
+	case EmptyWidget:
+		return w.Widget
+	case *EmptyWidget:
+		return w.Widget
+	case ContentWidget:
+		return w.Widget
+	case *ContentWidget:
+		return w.Widget
+	case LazyWidget:
+		return w.Widget
+	case *LazyWidget:
+		return w.Widget
+	case MediaWidget:
+		return w.Widget
+	case *MediaWidget:
+		return w.Widget
+	case GridWidget:
+		return w.Widget
+	case *GridWidget:
+		return w.Widget
+	case nil:
+		return nil
+	}
+
+	rv := reflect.ValueOf(w)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).Anonymous && rv.Field(i).Type() == widgetPtrType {
+			b, _ := rv.Field(i).Interface().(*Widget)
+			return b
+		}
+	}
+	return nil
+}
+
+// ensureBaseWidget returns embedded *Widget of w allocating it if w is
+// a pointer to a widget with nil *Widget. It returns nil if the allocated
+// value could not be kept (widget passed by value).
+func ensureBaseWidget(w Widgeter) *Widget {
+	if b := BaseWidget(w); b != nil {
+		return b
+	}
+
+	rv := reflect.ValueOf(w)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).Anonymous && rv.Field(i).Type() == widgetPtrType && rv.Field(i).CanSet() {
+			b := &Widget{Type: w.WidgetType()}
+			rv.Field(i).Set(reflect.ValueOf(b))
+			return b
+		}
+	}
+	return nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}